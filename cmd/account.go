@@ -1,13 +1,32 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/orionnectar/go-azbutils/internal/azure"
 	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/secret"
 	"github.com/spf13/cobra"
 )
 
+var (
+	secretBackend     string
+	assumeYes         bool
+	keepSecret        bool
+	exportFile        string
+	overwriteAccounts bool
+)
+
 var accountCmd = &cobra.Command{
 	Use:   "account",
 	Short: "Manage multiple Azure storage accounts",
@@ -19,41 +38,32 @@ var accountAddCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
-		cfg, _ := config.Load()
-		if cfg == nil {
-			cfg = &config.Config{Accounts: make(map[string]*config.AccountConfig)}
+		cfg, err := config.LoadUser()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
-
-		acct := &config.AccountConfig{}
-		// Interactive survey
-		authOptions := []string{"az-login", "connection-string", "shared-key", "sas"}
-		survey.AskOne(&survey.Select{
-			Message: "Choose auth method:",
-			Options: authOptions,
-		}, &acct.AuthMethod)
-
-		survey.AskOne(&survey.Input{Message: "Service URL (e.g. https://account.blob.core.windows.net):"}, &acct.ServiceURL)
-
-		switch acct.AuthMethod {
-		case "connection-string":
-			survey.AskOne(&survey.Password{Message: "Connection string:"}, &acct.Connection)
-		case "shared-key":
-			survey.AskOne(&survey.Input{Message: "Account name:"}, &acct.AccountName)
-			survey.AskOne(&survey.Password{Message: "Account key:"}, &acct.AccountKey)
-		case "sas":
-			survey.AskOne(&survey.Input{Message: "SAS URL (with ?sig=...):"}, &acct.ServiceURL)
+		if _, exists := cfg.Accounts[name]; exists {
+			return fmt.Errorf("account '%s' already exists (use 'account edit %s')", name, name)
 		}
 
-		cfg.Accounts[name] = acct
-		if cfg.DefaultAccount == "" {
-			cfg.DefaultAccount = name
+		acct, _, err := runSetupWizard(cmd, name, nil)
+		if err != nil {
+			return err
 		}
 
-		if err := config.Save(cfg); err != nil {
+		var defaultAccount string
+		err = config.Update(func(cfg *config.Config) error {
+			cfg.Accounts[name] = acct
+			if cfg.DefaultAccount == "" {
+				cfg.DefaultAccount = name
+			}
+			defaultAccount = cfg.DefaultAccount
+			return nil
+		})
+		if err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
-
-		fmt.Printf("✅ Added account '%s' (default: %s)\n", name, cfg.DefaultAccount)
+		fmt.Printf("✅ Added account '%s' (default: %s)\n", name, defaultAccount)
 		return nil
 	},
 }
@@ -84,24 +94,474 @@ var accountSetDefaultCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
-		cfg, err := config.Load()
+		err := config.Update(func(cfg *config.Config) error {
+			if _, ok := cfg.Accounts[name]; !ok {
+				return fmt.Errorf("account '%s' not found", name)
+			}
+			cfg.DefaultAccount = name
+			return nil
+		})
 		if err != nil {
 			return err
 		}
+		fmt.Printf("✅ Set '%s' as default account\n", name)
+		return nil
+	},
+}
+
+var accountRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove an account and its stored secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		cfg, err := config.LoadUser()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
 		if _, ok := cfg.Accounts[name]; !ok {
 			return fmt.Errorf("account '%s' not found", name)
 		}
-		cfg.DefaultAccount = name
-		if err := config.Save(cfg); err != nil {
+
+		if !assumeYes {
+			confirmed := false
+			if err := survey.AskOne(&survey.Confirm{Message: fmt.Sprintf("Remove account '%s'?", name)}, &confirmed); err != nil {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+		}
+
+		var acct *config.AccountConfig
+		err = config.Update(func(c *config.Config) error {
+			var ok bool
+			if acct, ok = c.Accounts[name]; !ok {
+				return fmt.Errorf("account '%s' not found", name)
+			}
+			delete(c.Accounts, name)
+			if c.DefaultAccount == name {
+				c.DefaultAccount = ""
+			}
+			cfg = c
+			return nil
+		})
+		if err != nil {
 			return err
 		}
-		fmt.Printf("✅ Set '%s' as default account\n", name)
+
+		if acct.SecretRef != "" && !keepSecret {
+			if err := deleteAccountSecret(acct.SecretRef); err != nil {
+				fmt.Printf("⚠️  Account removed but its secret could not be deleted: %v\n", err)
+			}
+		}
+
+		fmt.Printf("✅ Removed account '%s'\n", name)
+		if cfg.DefaultAccount == "" && len(cfg.Accounts) > 0 {
+			fmt.Println("No default account set. Use 'azbutils account default <name>' to pick one.")
+		}
+		return nil
+	},
+}
+
+var accountRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename an account",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldName, newName := args[0], args[1]
+		cfg, err := config.LoadUser()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		acct, ok := cfg.Accounts[oldName]
+		if !ok {
+			return fmt.Errorf("account '%s' not found", oldName)
+		}
+		if _, exists := cfg.Accounts[newName]; exists {
+			return fmt.Errorf("account '%s' already exists", newName)
+		}
+
+		// A secret stored under the account name moves to the new name; the
+		// old entry is deleted once the config points at the new one
+		oldRef := acct.SecretRef
+		newRef, err := copyAccountSecret(oldRef, oldName, newName)
+		if err != nil {
+			return err
+		}
+
+		err = config.Update(func(cfg *config.Config) error {
+			acct, ok := cfg.Accounts[oldName]
+			if !ok {
+				return fmt.Errorf("account '%s' not found", oldName)
+			}
+			if _, exists := cfg.Accounts[newName]; exists {
+				return fmt.Errorf("account '%s' already exists", newName)
+			}
+			if acct.SecretRef != oldRef {
+				return fmt.Errorf("account '%s' was changed concurrently; try again", oldName)
+			}
+
+			delete(cfg.Accounts, oldName)
+			acct.SecretRef = newRef
+			cfg.Accounts[newName] = acct
+			if cfg.DefaultAccount == oldName {
+				cfg.DefaultAccount = newName
+			}
+			oldPrefix := fmt.Sprintf("az://%s//", oldName)
+			for alias, target := range cfg.Aliases {
+				if rest, ok := strings.CutPrefix(target, oldPrefix); ok {
+					cfg.Aliases[alias] = fmt.Sprintf("az://%s//%s", newName, rest)
+				}
+			}
+			return nil
+		})
+		if newRef != oldRef {
+			// Whichever entry the config does not point at is left over
+			leftover := oldRef
+			if err != nil {
+				leftover = newRef
+			}
+			if delErr := deleteAccountSecret(leftover); delErr != nil {
+				fmt.Printf("⚠️  Could not delete the secret %s: %v\n", leftover, delErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Renamed account '%s' to '%s'\n", oldName, newName)
+		return nil
+	},
+}
+
+var accountShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show account settings (secrets redacted)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		acct, ok := cfg.Accounts[name]
+		if !ok {
+			return fmt.Errorf("account '%s' not found", name)
+		}
+
+		secretSource := acct.SecretRef
+		if secretSource == "" {
+			if suffix := azure.SecretEnvSuffix(acct.AuthMethod); suffix != "" {
+				secretSource = "env " + azure.SecretEnvName(acct, suffix)
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Name:\t%s\n", name)
+		fmt.Fprintf(w, "Default:\t%t\n", name == cfg.DefaultAccount)
+		fmt.Fprintf(w, "Auth method:\t%s\n", acct.AuthMethod)
+		printField(w, "Account name:", acct.AccountName)
+		printField(w, "Service URL:", redactURL(acct.ServiceURL))
+		printField(w, "Cloud:", acct.Cloud)
+		printField(w, "Endpoint suffix:", acct.EndpointSuffix)
+		printField(w, "Tenant ID:", acct.TenantID)
+		printField(w, "Client ID:", acct.ClientID)
+		printField(w, "Certificate path:", acct.CertificatePath)
+		printField(w, "Token file:", acct.TokenFilePath)
+		printField(w, "Secret:", secretSource)
+		return w.Flush()
+	},
+}
+
+var accountEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edit an account interactively or from flags",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		cfg, err := config.LoadUser()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		acct, ok := cfg.Accounts[name]
+		if !ok {
+			return fmt.Errorf("account '%s' not found", name)
+		}
+
+		edited, _, err := runSetupWizard(cmd, name, acct)
+		if err != nil {
+			return err
+		}
+
+		err = config.Update(func(cfg *config.Config) error {
+			cfg.Accounts[name] = edited
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("✅ Updated account '%s'\n", name)
+		return nil
+	},
+}
+
+var accountTestCmd = &cobra.Command{
+	Use:   "test [name...]",
+	Short: "Test connectivity of all (or the given) accounts in parallel",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		names := args
+		if len(names) == 0 {
+			for name := range cfg.Accounts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+
+		type result struct {
+			err     error
+			elapsed time.Duration
+		}
+		results := make([]result, len(names))
+		var wg sync.WaitGroup
+		for i, name := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				results[i].err = testAccount(cfg, name)
+				results[i].elapsed = time.Since(start)
+			}()
+		}
+		wg.Wait()
+
+		failed := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ACCOUNT\tSTATUS\tTIME\tDETAIL")
+		for i, name := range names {
+			status, detail := "✅ ok", ""
+			if results[i].err != nil {
+				failed++
+				status, detail = "❌ failed", results[i].err.Error()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, status, results[i].elapsed.Round(time.Millisecond), detail)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d account(s) failed the connection test", failed, len(names))
+		}
 		return nil
 	},
 }
 
+// accountExport is the file format of account export/import. It carries
+// metadata only; secrets and local secret references are never exported.
+type accountExport struct {
+	Accounts map[string]*config.AccountConfig `json:"accounts"`
+}
+
+var accountExportCmd = &cobra.Command{
+	Use:   "export [name...]",
+	Short: "Export account metadata (no secrets) as JSON",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		export := accountExport{Accounts: make(map[string]*config.AccountConfig)}
+		names := args
+		if len(names) == 0 {
+			for name := range cfg.Accounts {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			acct, ok := cfg.Accounts[name]
+			if !ok {
+				return fmt.Errorf("account '%s' not found", name)
+			}
+			exported := *acct
+			exported.SecretRef = ""
+			exported.ServiceURL = redactURL(acct.ServiceURL)
+			export.Accounts[name] = &exported
+		}
+
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize accounts: %w", err)
+		}
+		data = append(data, '\n')
+
+		if exportFile == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(exportFile, data, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", exportFile, err)
+		}
+		fmt.Printf("✅ Exported %d account(s) to %s\n", len(export.Accounts), exportFile)
+		return nil
+	},
+}
+
+var accountImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import account metadata exported with 'account export'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+		var imported accountExport
+		if err := json.Unmarshal(data, &imported); err != nil {
+			return fmt.Errorf("invalid account export: %w", err)
+		}
+
+		names := make([]string, 0, len(imported.Accounts))
+		for name := range imported.Accounts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			acct := imported.Accounts[name]
+			if acct == nil {
+				return fmt.Errorf("invalid account export: account '%s' is empty", name)
+			}
+			if err := azure.ValidateAccount(acct, ""); err != nil {
+				return fmt.Errorf("invalid account export: account '%s': %w", name, err)
+			}
+		}
+
+		added := 0
+		err = config.Update(func(cfg *config.Config) error {
+			for _, name := range names {
+				acct := imported.Accounts[name]
+				if _, exists := cfg.Accounts[name]; exists && !overwriteAccounts {
+					fmt.Printf("Skipping '%s': already exists (use --overwrite to replace)\n", name)
+					continue
+				}
+				acct.SecretRef = ""
+				cfg.Accounts[name] = acct
+				added++
+
+				if suffix := azure.SecretEnvSuffix(acct.AuthMethod); suffix != "" {
+					fmt.Printf("Imported '%s'. Provide its secret with 'azbutils account edit %s' or export %s\n",
+						name, name, azure.SecretEnvName(acct, suffix))
+				} else {
+					fmt.Printf("Imported '%s'\n", name)
+				}
+			}
+			if cfg.DefaultAccount == "" && len(names) > 0 {
+				cfg.DefaultAccount = names[0]
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("✅ Imported %d account(s)\n", added)
+		return nil
+	},
+}
+
+func testAccount(cfg *config.Config, name string) error {
+	acct, ok := cfg.Accounts[name]
+	if !ok {
+		return fmt.Errorf("account not found")
+	}
+	client, err := azure.ClientFor(name, acct)
+	if err != nil {
+		return err
+	}
+	return testConnection(client)
+}
+
+func deleteAccountSecret(ref string) error {
+	backend, key, err := secret.ParseRef(ref)
+	if err != nil {
+		return err
+	}
+	store, err := secret.Open(backend)
+	if err != nil {
+		return err
+	}
+	return store.Delete(key)
+}
+
+// copyAccountSecret copies a secret stored under the account name oldName to
+// newName and returns the reference to the copy. References to environment
+// variables or to keys not named after the account are returned unchanged.
+func copyAccountSecret(ref, oldName, newName string) (string, error) {
+	if ref == "" {
+		return ref, nil
+	}
+	backend, key, err := secret.ParseRef(ref)
+	if err != nil {
+		return "", err
+	}
+	if backend == secret.BackendEnv || key != oldName {
+		return ref, nil
+	}
+	store, err := secret.Open(backend)
+	if err != nil {
+		return "", err
+	}
+	value, err := store.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", ref, err)
+	}
+	if err := store.Set(newName, value); err != nil {
+		return "", fmt.Errorf("failed to store secret: %w", err)
+	}
+	return secret.Ref(backend, newName), nil
+}
+
+func printField(w io.Writer, label, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "%s\t%s\n", label, value)
+	}
+}
+
+// redactURL hides the SAS signature of a URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	if q.Has("sig") {
+		q.Set("sig", "REDACTED")
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
 func init() {
+	addSetupFlags(accountAddCmd)
+	addSetupFlags(accountEditCmd)
+
+	accountRmCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Do not ask for confirmation")
+	accountRmCmd.Flags().BoolVar(&keepSecret, "keep-secret", false, "Keep the account's secret in its secret store")
+	accountExportCmd.Flags().StringVarP(&exportFile, "output", "o", "", "Write the export to a file instead of stdout")
+	accountImportCmd.Flags().BoolVar(&overwriteAccounts, "overwrite", false, "Replace accounts that already exist")
+
 	accountCmd.AddCommand(accountAddCmd)
 	accountCmd.AddCommand(accountListCmd)
 	accountCmd.AddCommand(accountSetDefaultCmd)
+	accountCmd.AddCommand(accountRmCmd)
+	accountCmd.AddCommand(accountRenameCmd)
+	accountCmd.AddCommand(accountShowCmd)
+	accountCmd.AddCommand(accountEditCmd)
+	accountCmd.AddCommand(accountTestCmd)
+	accountCmd.AddCommand(accountExportCmd)
+	accountCmd.AddCommand(accountImportCmd)
 }
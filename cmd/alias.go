@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage path aliases (bookmarks)",
+	Long: `Manage path aliases. An alias "@name" expands to a saved path prefix
+wherever a remote path is accepted.
+
+Examples:
+  azbutils alias add logs az://myaccount//logs/app/prod/
+  azbutils ls @logs
+  azbutils cat @logs/2024-01-01.log
+`,
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <name> <path>",
+	Short: "Add or update an alias",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimPrefix(args[0], "@")
+		target := args[1]
+		if !aliasNamePattern.MatchString(name) {
+			return usageErrorf("invalid alias name '%s' (use letters, digits, '-' and '_')", name)
+		}
+		if strings.HasPrefix(target, "@") {
+			return usageErrorf("an alias must point to a path, not another alias")
+		}
+		if _, err := parsePath(target); err != nil {
+			return fmt.Errorf("invalid alias target: %w", err)
+		}
+
+		err := config.Update(func(cfg *config.Config) error {
+			if cfg.Aliases == nil {
+				cfg.Aliases = make(map[string]string)
+			}
+			cfg.Aliases[name] = target
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("✅ Added alias @%s → %s\n", name, target)
+		return nil
+	},
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List aliases",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if len(cfg.Aliases) == 0 {
+			fmt.Println("No aliases.")
+			return nil
+		}
+
+		names := make([]string, 0, len(cfg.Aliases))
+		for name := range cfg.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Println("Aliases:")
+		for _, name := range names {
+			fmt.Printf(" - @%s → %s\n", name, cfg.Aliases[name])
+		}
+		return nil
+	},
+}
+
+var aliasRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove an alias",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimPrefix(args[0], "@")
+		err := config.Update(func(cfg *config.Config) error {
+			if _, ok := cfg.Aliases[name]; !ok {
+				return fmt.Errorf("alias '@%s' not found", name)
+			}
+			delete(cfg.Aliases, name)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Removed alias @%s\n", name)
+		return nil
+	},
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasAddCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	aliasCmd.AddCommand(aliasRmCmd)
+}
@@ -1,79 +1,58 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"os"
-	"time"
 
-	"github.com/orionnectar/go-azbutils/internal/azpath"
-	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/atomicfile"
 	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
 	"github.com/spf13/cobra"
 )
 
-var outputFile string
+var (
+	outputFile   string
+	catVersionID string
+)
 
 var catCmd = &cobra.Command{
-	Use:   "cat <az://account//container/blob>",
+	Use:   "cat <az://account//container/blob[#versionId]>",
 	Short: "Print the contents of a blob or save it to a local file",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		p, err := azpath.Parse(args[0])
+		mode, err := checksumMode()
 		if err != nil {
 			return err
 		}
-
-		cfg, err := config.Load()
+		client, err := newLibClient()
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
-		}
-
-		acctCfg := cfg.Accounts[p.Account]
-		if acctCfg == nil {
-			return fmt.Errorf("no account found in config for '%s'", p.Account)
-		}
-
-		client, err := azure.NewClientFromConfigAccount(acctCfg)
-		if err != nil {
-			return fmt.Errorf("failed to create Azure client: %w", err)
+			return err
 		}
 
-		containerClient := client.ServiceClient().NewContainerClient(p.Container)
-		blobClient := containerClient.NewBlockBlobClient(p.SubPath)
-
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		ctx, cancel := newContext(config.OpTransfer)
 		defer cancel()
 
-		resp, err := blobClient.DownloadStream(ctx, nil)
-		if err != nil {
-			return fmt.Errorf("failed to download blob: %w", err)
+		opts := &azbutils.CatOptions{
+			VersionID:          catVersionID,
+			CheckMD5:           mode,
+			OnChecksumMismatch: warnChecksumMismatch,
 		}
-
-		reader := resp.NewRetryReader(ctx, nil)
-		defer reader.Close()
-
 		if outputFile == "" {
 			// Stream to stdout
-			_, err = io.Copy(os.Stdout, reader)
-			if err != nil {
-				return fmt.Errorf("failed to stream blob: %w", err)
-			}
-			return nil
-		}
-
-		// Save to file
-		out, err := os.Create(outputFile)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
+			_, err = client.Cat(ctx, args[0], os.Stdout, opts)
+			return err
 		}
-		defer out.Close()
 
-		fmt.Printf("Downloading blob '%s' → %s\n", p.SubPath, outputFile)
-		_, err = io.Copy(out, reader)
+		// Save to a temporary file first, so an interrupted download
+		// leaves no partial output behind
+		fmt.Printf("Downloading blob '%s' → %s\n", args[0], outputFile)
+		err = atomicfile.WriteFile(outputFile, 0666, func(w io.Writer) error {
+			_, err := client.Cat(ctx, args[0], w, opts)
+			return err
+		})
 		if err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
+			return err
 		}
 
 		fmt.Println("Blob saved successfully")
@@ -83,4 +62,6 @@ var catCmd = &cobra.Command{
 
 func init() {
 	catCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write blob contents to a local file instead of stdout")
+	catCmd.Flags().StringVar(&catVersionID, "version-id", "", "Read a specific blob version instead of the current one")
+	addCheckMD5Flag(catCmd)
 }
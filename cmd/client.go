@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/store"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
+)
+
+// parsePath parses a remote path, expanding aliases and recognising the
+// endpoints of configured accounts
+func parsePath(input string) (*azpath.BlobPath, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	p, err := newPathParser(cfg).Parse(input)
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+	return p, nil
+}
+
+// newPathParser builds a path parser that knows the accounts and aliases in cfg
+func newPathParser(cfg *config.Config) *azpath.Parser {
+	return azure.NewPathParser(cfg, accountFlag)
+}
+
+// accountForPath loads the config and returns the account referenced by p
+func accountForPath(p *azpath.BlobPath) (*config.AccountConfig, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	acctCfg := cfg.Accounts[p.Account]
+	if acctCfg == nil {
+		return nil, fmt.Errorf("no account found in config for '%s'", p.Account)
+	}
+	return acctCfg, nil
+}
+
+// clientForPath loads the config and returns the shared client of the account in p
+func clientForPath(p *azpath.BlobPath) (*azblob.Client, error) {
+	acctCfg, err := accountForPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := azure.ClientFor(p.Account, acctCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+	return client, nil
+}
+
+// storeForPath returns the blob store of the account in p
+func storeForPath(p *azpath.BlobPath) (store.Store, error) {
+	client, err := clientForPath(p)
+	if err != nil {
+		return nil, err
+	}
+	return store.NewBlobStore(client), nil
+}
+
+// newLibClient returns a library client for the current config and --account
+func newLibClient() (*azbutils.Client, error) {
+	return azbutils.New(&azbutils.Options{Account: accountFlag})
+}
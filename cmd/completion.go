@@ -12,7 +12,7 @@ var completionCmd = &cobra.Command{
 	Short:     "Generate completion script for your shell",
 	Args:      cobra.ExactValidArgs(1),
 	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		shell := args[0]
 		var err error
 
@@ -26,13 +26,12 @@ var completionCmd = &cobra.Command{
 		case "powershell":
 			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
 		default:
-			fmt.Println("Unsupported shell:", shell)
-			os.Exit(1)
+			return usageErrorf("unsupported shell: %s", shell)
 		}
 
 		if err != nil {
-			fmt.Println("Error generating completion:", err)
-			os.Exit(1)
+			return fmt.Errorf("failed to generate completion: %w", err)
 		}
+		return nil
 	},
 }
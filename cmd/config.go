@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/secret"
+	"github.com/spf13/cobra"
+)
+
+var showUserConfig bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit the config file",
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file path",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.ConfigPath()
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		if project := config.ProjectPath(); project != "" {
+			fmt.Fprintf(os.Stderr, "Overlaid by project config %s\n", project)
+		}
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective config (SAS signatures redacted)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		load := config.Load
+		if showUserConfig {
+			load = config.LoadUser
+		}
+		cfg, err := load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		for _, acct := range cfg.Accounts {
+			acct.ServiceURL = redactURL(acct.ServiceURL)
+		}
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize config: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config for errors",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		problems := validateConfig(cfg)
+		for _, p := range problems {
+			fmt.Println("❌", p)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("config has %d problem(s)", len(problems))
+		}
+		fmt.Printf("✅ Config is valid (%d account(s), %d alias(es))\n", len(cfg.Accounts), len(cfg.Aliases))
+		return nil
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in $VISUAL or $EDITOR",
+	Long: `Open a copy of the config file in $VISUAL or $EDITOR. The file is only
+replaced if the edited copy parses and validates; other azbutils invocations
+wait until the editor exits.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return config.Update(func(cfg *config.Config) error {
+			data, err := json.MarshalIndent(cfg, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to serialize config: %w", err)
+			}
+			tmp, err := os.CreateTemp("", "azbutils-config-*.json")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			_, err = tmp.Write(data)
+			if closeErr := tmp.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := runEditor(tmp.Name()); err != nil {
+				return err
+			}
+
+			data, err = os.ReadFile(tmp.Name())
+			if err != nil {
+				return err
+			}
+			edited, err := config.Parse(data, "edited config")
+			if err != nil {
+				return fmt.Errorf("%w; changes discarded", err)
+			}
+			if problems := validateConfig(edited); len(problems) > 0 {
+				for _, p := range problems {
+					fmt.Println("❌", p)
+				}
+				return fmt.Errorf("edited config has %d problem(s); changes discarded", len(problems))
+			}
+			*cfg = *edited
+			fmt.Println("✅ Config saved")
+			return nil
+		})
+	},
+}
+
+// runEditor opens path in the user's editor and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	return nil
+}
+
+// validateConfig lists the problems of cfg, one message per problem
+func validateConfig(cfg *config.Config) []string {
+	var problems []string
+	if cfg.DefaultAccount != "" && cfg.Accounts[cfg.DefaultAccount] == nil {
+		problems = append(problems, fmt.Sprintf("default account '%s' does not exist", cfg.DefaultAccount))
+	}
+
+	names := make([]string, 0, len(cfg.Accounts))
+	for name := range cfg.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		acct := cfg.Accounts[name]
+		if acct == nil {
+			problems = append(problems, fmt.Sprintf("account '%s': empty entry", name))
+			continue
+		}
+		if err := azure.ValidateAccount(acct, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("account '%s': %v", name, err))
+		}
+		if acct.SecretRef != "" {
+			if _, _, err := secret.ParseRef(acct.SecretRef); err != nil {
+				problems = append(problems, fmt.Sprintf("account '%s': %v", name, err))
+			}
+		}
+	}
+
+	if err := cfg.Network.Validate(); err != nil {
+		problems = append(problems, fmt.Sprintf("network: %v", err))
+	}
+
+	aliases := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		aliases = append(aliases, name)
+	}
+	sort.Strings(aliases)
+	parser := newPathParser(cfg)
+	for _, name := range aliases {
+		if !aliasNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("alias '%s': invalid name", name))
+			continue
+		}
+		if _, err := parser.Parse(cfg.Aliases[name]); err != nil {
+			problems = append(problems, fmt.Sprintf("alias '@%s': %v", name, err))
+		}
+	}
+	return problems
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&showUserConfig, "user", false, "Show the user config without the project overlay")
+
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configEditCmd)
+}
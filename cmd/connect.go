@@ -2,18 +2,19 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"strings"
 
-	"github.com/AlecAivazis/survey/v2"
 	"github.com/orionnectar/go-azbutils/internal/azure"
 	"github.com/orionnectar/go-azbutils/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var (
-	resetConfig bool
-	useAzLogin  bool
+	resetConfig    bool
+	useAzLogin     bool
+	cloudName      string
+	endpointSuffix string
+	useEmulator    bool
+	emulatorURL    string
 )
 
 var connectCmd = &cobra.Command{
@@ -21,15 +22,18 @@ var connectCmd = &cobra.Command{
 	Short: "Connect to an Azure Storage account and verify connection",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, _ := config.Load()
-		if cfg == nil {
-			cfg = &config.Config{Accounts: make(map[string]*config.AccountConfig)}
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		var accountName string
-		if len(args) == 1 {
+		switch {
+		case len(args) == 1:
 			accountName = args[0]
-		} else {
+		case useEmulator:
+			accountName = azure.EmulatorAccountName
+		default:
 			accountName = cfg.DefaultAccount
 		}
 
@@ -38,66 +42,50 @@ var connectCmd = &cobra.Command{
 		}
 
 		// Only run setup if account metadata missing or reset requested
-		if resetConfig || cfg.Accounts[accountName] == nil {
+		if resetConfig || useEmulator || cfg.Accounts[accountName] == nil {
 			var acctCfg *config.AccountConfig
+			var connected bool
 			var err error
-			if useAzLogin {
-				acctCfg, err = setupAzLogin(accountName)
+			if useEmulator {
+				acctCfg = setupEmulator(accountName)
+				connected, err = testBeforeSave(acctCfg, "")
 			} else {
-				acctCfg, err = interactiveSetup(accountName)
+				if useAzLogin {
+					cmd.Flags().Set("auth", "az-login")
+					nonInteractive = true
+				}
+				acctCfg, connected, err = runSetupWizard(cmd, accountName, nil)
 			}
 			if err != nil {
 				return err
 			}
 
-			cfg.Accounts[accountName] = acctCfg
-			if cfg.DefaultAccount == "" {
-				cfg.DefaultAccount = accountName
-			}
-
-			if err := config.Save(cfg); err != nil {
+			err = config.Update(func(userCfg *config.Config) error {
+				userCfg.Accounts[accountName] = acctCfg
+				if userCfg.DefaultAccount == "" {
+					userCfg.DefaultAccount = accountName
+				}
+				return nil
+			})
+			if err != nil {
 				return fmt.Errorf("failed to save config: %w", err)
 			}
 
 			fmt.Printf("Account '%s' saved\n", accountName)
-		}
-
-		acctCfg := cfg.Accounts[accountName]
-		envName := strings.ToUpper(accountName)
-		// Populate secret in the AccountConfig from environment
-		switch acctCfg.AuthMethod {
-		case "connection-string":
-			acctCfg.Connection = os.Getenv(fmt.Sprintf("%s_CONNECTION_STRING", envName))
-			if acctCfg.Connection == "" {
-				return fmt.Errorf("missing environment variable: %s_CONNECTION_STRING", envName)
-			}
-		case "shared-key":
-			acctCfg.AccountKey = os.Getenv(fmt.Sprintf("%s_ACCOUNT_KEY", envName))
-			if acctCfg.AccountKey == "" {
-				return fmt.Errorf("missing environment variable: %s_ACCOUNT_KEY", envName)
-			}
-		case "sas":
-			acctCfg.ServiceURL = os.Getenv(fmt.Sprintf("%s_SAS_URL", envName))
-			if acctCfg.ServiceURL == "" {
-				return fmt.Errorf("missing environment variable: %s_SAS_URL", envName)
+			if connected {
+				fmt.Printf("Successfully connected to account '%s'\n", accountName)
 			}
-		case "az-login":
-			// no action needed, will use Azure CLI credential internally
-		default:
-			return fmt.Errorf("unsupported auth method: %s", acctCfg.AuthMethod)
-		}
-
-		client, err := azure.NewClientFromConfigAccount(acctCfg)
-		if err != nil {
-			return fmt.Errorf("failed to create client: %w", err)
+			return nil
 		}
 
+		acctCfg := cfg.Accounts[accountName]
+		client, err := azure.ClientFor(accountName, acctCfg)
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
 
 		fmt.Println("Testing connection...")
-		if err := azure.TestConnection(client); err != nil {
+		if err := testConnection(client); err != nil {
 			return fmt.Errorf("connection test failed: %w", err)
 		}
 
@@ -106,32 +94,21 @@ var connectCmd = &cobra.Command{
 	},
 }
 
-func setupAzLogin(name string) (*config.AccountConfig, error) {
-	defaultURL := fmt.Sprintf("https://%s.blob.core.windows.net", name)
+// setupEmulator creates a preset for the Azurite well-known development account
+func setupEmulator(name string) *config.AccountConfig {
 	acct := &config.AccountConfig{
-		AccountName: name,
-		ServiceURL:  defaultURL,
-		AuthMethod:  "az-login",
+		AccountName: azure.EmulatorAccountName,
+		ServiceURL:  azure.EmulatorServiceURL(emulatorURL),
+		AuthMethod:  "emulator",
 	}
-	fmt.Printf("Using Azure CLI credentials for account '%s' with Service URL '%s'\n", name, defaultURL)
-	return acct, nil
-}
-
-func interactiveSetup(name string) (*config.AccountConfig, error) {
-	acct := &config.AccountConfig{AccountName: name}
-	authOptions := []string{"az-login", "connection-string", "shared-key", "sas"}
-
-	survey.AskOne(&survey.Select{
-		Message: "Choose auth method:",
-		Options: authOptions,
-	}, &acct.AuthMethod)
-
-	// Service URL is always set based on account name
-	acct.ServiceURL = fmt.Sprintf("https://%s.blob.core.windows.net", name)
-	return acct, nil
+	fmt.Printf("Using local emulator account '%s' at '%s'\n", name, acct.ServiceURL)
+	return acct
 }
 
 func init() {
 	connectCmd.Flags().BoolVar(&resetConfig, "reset", false, "Reset metadata for this account")
-	connectCmd.Flags().BoolVar(&useAzLogin, "use-az-login", false, "Use Azure CLI login credentials")
+	connectCmd.Flags().BoolVar(&useAzLogin, "use-az-login", false, "Use Azure CLI login credentials (same as --auth az-login --non-interactive)")
+	connectCmd.Flags().BoolVar(&useEmulator, "emulator", false, "Configure the Azurite local emulator account (devstoreaccount1)")
+	connectCmd.Flags().StringVar(&emulatorURL, "emulator-url", azure.EmulatorBlobURL, "Azurite blob endpoint used with --emulator")
+	addSetupFlags(connectCmd)
 }
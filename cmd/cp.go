@@ -2,26 +2,32 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
-	"github.com/orionnectar/go-azbutils/internal/azpath"
-	"github.com/orionnectar/go-azbutils/internal/azure"
 	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dryRun bool
+	dryRun            bool
+	cpVersionID       string
+	noClobber         bool
+	ifMatch           string
+	ifUnmodifiedSince string
+	skipExisting      bool
+	overwrite         string
 )
 
 var cpCmd = &cobra.Command{
 	Use:   "cp <source> <destination>",
-	Short: "Copy a local file or directory to Azure Blob Storage",
-	Long: `Upload a single file or an entire directory to Azure Blob Storage.
+	Short: "Copy files between the local filesystem and Azure Blob Storage",
+	Long: `Upload a single file or an entire directory to Azure Blob Storage,
+or download blobs to the local filesystem.
 
 Examples:
   # Upload a single file
@@ -32,119 +38,200 @@ Examples:
 
   # Dry run (show what would be uploaded)
   azbutils cp ./data az://myaccount//container/data -r --dry-run
+
+  # Download a blob
+  azbutils cp az://myaccount//mycontainer/myfile.txt ./myfile.txt
+
+  # Download even if the data does not match the stored MD5
+  azbutils cp az://myaccount//mycontainer/myfile.txt ./myfile.txt --check-md5 warn
+
+  # Upload only if the blob does not exist yet, atomically
+  azbutils cp ./result.json az://myaccount//mycontainer/result.json --no-clobber
+
+  # Replace a blob only if nobody changed it since it was read
+  azbutils cp ./result.json az://myaccount//mycontainer/result.json --if-match '"0x8DC..."'
+
+  # Upload only files newer than their blobs
+  azbutils cp ./data az://myaccount//mycontainer/data -r --overwrite ifSourceNewer
+
+  # Download a previous version of a blob
+  azbutils cp az://myaccount//mycontainer/myfile.txt ./old.txt --version-id 2024-01-01T00:00:00.0000000Z
 `,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		src := args[0]
 		dst := args[1]
 
-		info, err := os.Stat(src)
+		mode, err := checksumMode()
 		if err != nil {
-			return fmt.Errorf("failed to access source: %w", err)
+			return err
+		}
+		policy, conditions, err := writePolicy(cmd, azbutils.IsRemote(src))
+		if err != nil {
+			return err
+		}
+		client, err := newLibClient()
+		if err != nil {
+			return err
 		}
 
-		if info.IsDir() && !recursive {
-			return fmt.Errorf("'%s' is a directory. Use -r or --recursive to upload recursively", src)
+		ctx, cancel, interrupt := newDrainContext(config.OpTransfer)
+		defer cancel()
+
+		opts := &azbutils.CopyOptions{
+			Recursive:          recursive,
+			DryRun:             dryRun,
+			VersionID:          cpVersionID,
+			LeaseID:            leaseID,
+			Overwrite:          policy,
+			Conditions:         conditions,
+			OnTransfer:         printTransfer,
+			OnSkip:             printSkip,
+			CheckMD5:           mode,
+			OnChecksumMismatch: warnChecksumMismatch,
+			Interrupt:          interrupt,
 		}
 
-		// Parse the destination path (az:// or Azure URL)
-		p, err := azpath.Parse(dst)
+		if azbutils.IsRemote(src) {
+			if recursive && cpVersionID != "" {
+				return usageErrorf("--version-id cannot be used with --recursive")
+			}
+			if recursive {
+				fmt.Printf("Downloading %s recursively...\n", src)
+			}
+			if err := runCopy(ctx, client, src, dst, opts); err != nil {
+				return err
+			}
+			if recursive {
+				printDone("Directory download", "no files downloaded")
+			}
+			return nil
+		}
+
+		if cpVersionID != "" {
+			return usageErrorf("--version-id only applies to a remote source")
+		}
+		info, err := os.Stat(src)
 		if err != nil {
-			return fmt.Errorf("invalid destination path: %w", err)
+			return fmt.Errorf("failed to access source: %w", err)
+		}
+		if info.IsDir() && !recursive {
+			return usageErrorf("'%s' is a directory. Use -r or --recursive to upload recursively", src)
 		}
 
 		if info.IsDir() {
-			return uploadDirectory(src, p)
+			fmt.Printf("Uploading directory %s recursively...\n", src)
+		}
+		if err := runCopy(ctx, client, src, dst, opts); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			printDone("Directory upload", "no files uploaded")
 		}
-		return uploadFile(src, p)
+		return nil
 	},
 }
 
-func uploadFile(localPath string, p *azpath.BlobPath) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	acctCfg := cfg.Accounts[p.Account]
-	if acctCfg == nil {
-		return fmt.Errorf("no account found in config for '%s'", p.Account)
+// printTransfer announces a file copy, or what a dry run would copy
+func printTransfer(t azbutils.Transfer) {
+	verb, dryRunVerb := "Uploading", "upload"
+	if azbutils.IsRemote(t.Source) {
+		verb, dryRunVerb = "Downloading", "download"
 	}
-
-	client, err := azure.NewClientFromConfigAccount(acctCfg)
-	if err != nil {
-		return fmt.Errorf("failed to create Azure client: %w", err)
+	if dryRun {
+		fmt.Printf("[dry-run] Would %s %s → %s\n", dryRunVerb, t.Source, t.Destination)
+		return
 	}
+	fmt.Printf("%s %s → %s\n", verb, t.Source, t.Destination)
+}
 
-	containerClient := client.ServiceClient().NewContainerClient(p.Container)
-	blobClient := containerClient.NewBlockBlobClient(p.SubPath)
-
+// printSkip announces a file the overwrite policy leaves alone
+func printSkip(t azbutils.Transfer) {
+	prefix, reason := "", "destination exists"
 	if dryRun {
-		fmt.Printf("[dry-run] Would upload %s → %s\n", localPath, p.BuildFull(p.SubPath))
-		return nil
+		prefix = "[dry-run] "
 	}
-
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open local file: %w", err)
+	if overwrite == "ifSourceNewer" {
+		reason = "destination is up to date"
 	}
-	defer file.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	fmt.Printf("%sSkipping %s → %s (%s)\n", prefix, t.Source, t.Destination, reason)
+}
 
-	fmt.Printf("Uploading %s → %s\n", localPath, p.BuildFull(p.SubPath))
-	_, err = blobClient.UploadStream(ctx, file, &azblob.UploadStreamOptions{})
+// writePolicy returns the overwrite policy and upload conditions of the
+// flags, rejecting combinations that contradict each other
+func writePolicy(cmd *cobra.Command, download bool) (azbutils.OverwritePolicy, *azbutils.WriteConditions, error) {
+	policy, err := azbutils.ParseOverwritePolicy(overwrite)
 	if err != nil {
-		return fmt.Errorf("upload failed: %w", err)
+		return 0, nil, &usageError{err: err}
 	}
-	return nil
-}
-
-func uploadDirectory(localDir string, p *azpath.BlobPath) error {
-	fmt.Printf("Uploading directory %s recursively...\n", localDir)
-
-	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
+	if skipExisting {
+		if cmd.Flags().Changed("overwrite") && policy != azbutils.OverwriteNever {
+			return 0, nil, usageErrorf("--skip-existing conflicts with --overwrite %s", overwrite)
 		}
+		policy = azbutils.OverwriteNever
+	}
 
-		rel, err := filepath.Rel(localDir, path)
+	if !noClobber && ifMatch == "" && ifUnmodifiedSince == "" {
+		return policy, nil, nil
+	}
+	switch {
+	case download:
+		return 0, nil, usageErrorf("--no-clobber, --if-match and --if-unmodified-since only apply to uploads; use --skip-existing for downloads")
+	case policy != azbutils.OverwriteAlways:
+		return 0, nil, usageErrorf("--no-clobber, --if-match and --if-unmodified-since cannot be combined with --skip-existing or --overwrite")
+	case noClobber && ifMatch != "":
+		return 0, nil, usageErrorf("--no-clobber and --if-match are mutually exclusive")
+	}
+	conditions := &azbutils.WriteConditions{NoClobber: noClobber, IfMatch: ifMatch}
+	if ifUnmodifiedSince != "" {
+		t, err := parseTime(ifUnmodifiedSince)
 		if err != nil {
-			return err
-		}
-
-		dstPath := p.SubPath
-		if dstPath != "" {
-			dstPath += "/"
-		}
-		dstPath += filepath.ToSlash(rel)
-
-		dstBlob := &azpath.BlobPath{
-			Account:   p.Account,
-			Container: p.Container,
-			SubPath:   dstPath,
-			Type:      p.Type,
+			return 0, nil, &usageError{err: fmt.Errorf("invalid --if-unmodified-since: %w", err)}
 		}
+		conditions.IfUnmodifiedSince = t
+	}
+	return policy, conditions, nil
+}
 
-		return uploadFile(path, dstBlob)
-	})
+// parseTime accepts an RFC 3339 time or an HTTP date, as in Last-Modified
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := http.ParseTime(s)
 	if err != nil {
-		return fmt.Errorf("directory upload failed: %w", err)
+		return time.Time{}, fmt.Errorf("'%s' is neither an RFC 3339 time nor an HTTP date", s)
 	}
+	return t, nil
+}
 
+// printDone reports the end of a directory copy
+func printDone(what, dryRunNote string) {
 	if dryRun {
-		fmt.Println("[dry-run] Directory upload simulated — no files uploaded.")
+		fmt.Printf("[dry-run] %s simulated — %s.\n", what, dryRunNote)
 	} else {
-		fmt.Println("Directory upload complete.")
+		fmt.Printf("%s complete.\n", what)
+	}
+}
+
+// runCopy runs client.Copy, summarizing what was done if it was interrupted
+func runCopy(ctx context.Context, client *azbutils.Client, src, dst string, opts *azbutils.CopyOptions) error {
+	res, err := client.Copy(ctx, src, dst, opts)
+	if errors.Is(err, azbutils.ErrInterrupted) {
+		fmt.Printf("Interrupted after %d file(s), %d bytes.\n", len(res.Transfers), res.Bytes)
 	}
-	return nil
+	return err
 }
 
 func init() {
-	cpCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Upload directories recursively")
-	cpCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Preview upload actions without performing them")
+	cpCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Copy directories recursively")
+	cpCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Preview copy actions without performing them")
+	cpCmd.Flags().StringVar(&cpVersionID, "version-id", "", "Download a specific version of the source blob")
+	cpCmd.Flags().StringVar(&leaseID, "lease-id", "", "Lease ID required to overwrite a leased destination blob")
+	cpCmd.Flags().BoolVar(&noClobber, "no-clobber", false, "Fail instead of overwriting an existing blob (If-None-Match: *)")
+	cpCmd.Flags().StringVar(&ifMatch, "if-match", "", "Only overwrite a blob that still has this ETag")
+	cpCmd.Flags().StringVar(&ifUnmodifiedSince, "if-unmodified-since", "", "Only overwrite a blob not modified since this time (RFC 3339 or HTTP date)")
+	cpCmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "Skip files whose destination exists (same as --overwrite never)")
+	cpCmd.Flags().StringVar(&overwrite, "overwrite", "always", "When to replace an existing destination: always, never or ifSourceNewer")
+	addCheckMD5Flag(cpCmd)
 }
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/store"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes. They are part of the CLI's interface: scripts branch on them,
+// so never renumber them.
+const (
+	exitError        = 1 // anything not covered below
+	exitUsage        = 2
+	exitNotFound     = 3
+	exitAuth         = 4
+	exitThrottled    = 5
+	exitConflict     = 6
+	exitPrecondition = 7
+	exitPartial      = 8
+	// exitInterrupted (130) is defined with the signal handling
+)
+
+// usageError marks an invalid command line: unknown flags or commands, wrong
+// arguments, conflicting options
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// partialError reports a bulk operation in which some items failed; the
+// individual failures are printed as they happen
+type partialError struct {
+	op     string
+	total  int
+	failed []error
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("%s failed for %d of %d blob(s)", e.op, len(e.failed), e.total)
+}
+
+// Unwrap lets errors.Is and errors.As see the individual failures
+func (e *partialError) Unwrap() []error { return e.failed }
+
+// bulkResult returns nil if nothing failed, the failure if only one item was
+// attempted, and a partialError otherwise
+func bulkResult(op string, total int, failed []error) error {
+	switch {
+	case len(failed) == 0:
+		return nil
+	case total == 1:
+		return failed[0]
+	default:
+		return &partialError{op: op, total: total, failed: failed}
+	}
+}
+
+const preconditionHint = "A precondition did not hold: the blob changed since it was read."
+
+const leaseHint = "The blob is leased. Pass its lease with --lease-id, or release it with 'azbutils lease break'."
+
+// classify maps err to its exit code and a hint on how to fix it
+func classify(err error) (int, string) {
+	var usage *usageError
+	var partial *partialError
+	var checksum *azbutils.ChecksumError
+	switch {
+	case isInterrupted(err) || errors.Is(err, azbutils.ErrInterrupted):
+		return exitInterrupted, "Re-run the command to finish; completed transfers are kept."
+	case errors.As(err, &usage) || isCobraUsageError(err):
+		return exitUsage, ""
+	case errors.As(err, &partial):
+		return exitPartial, "Re-run the command to retry the failed blobs; the others are done."
+	case errors.Is(err, store.ErrExists):
+		return exitConflict, "The destination blob exists and was not overwritten; drop --no-clobber to replace it, or use --skip-existing to skip it."
+	case errors.As(err, &checksum):
+		return exitError, "The data was corrupted in transit, or the blob's stored MD5 is stale. Retry the download, or pass --check-md5 warn to keep it anyway."
+	}
+
+	leased := strings.HasPrefix(azure.ErrorCode(err), "Lease")
+	switch azure.ClassifyError(err) {
+	case azure.ErrorNotFound:
+		return exitNotFound, "Check the container and blob names with 'azbutils ls'."
+	case azure.ErrorAuth:
+		return exitAuth, fmt.Sprintf("Check the credentials with 'azbutils account test %s', or set them up again with 'azbutils connect %s --reset'. For SAS URLs, check the permissions (sp) and expiry (se); --debug shows both.", accountHint(), accountHint())
+	case azure.ErrorThrottled:
+		return exitThrottled, "The service is throttling requests. Retry later, or raise --max-retries and --retry-delay."
+	case azure.ErrorConflict:
+		if leased {
+			return exitConflict, leaseHint
+		}
+		return exitConflict, "The blob or container is in a conflicting state; retry once the other operation finished."
+	case azure.ErrorPrecondition:
+		if leased {
+			return exitPrecondition, leaseHint
+		}
+		return exitPrecondition, preconditionHint
+	}
+
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return exitNotFound, ""
+	case errors.Is(err, store.ErrConditionNotMet):
+		return exitPrecondition, preconditionHint
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitError, "The operation timed out; raise the limit with --timeout (0 for none)."
+	}
+	return exitError, ""
+}
+
+// isCobraUsageError recognizes the argument errors cobra creates itself
+func isCobraUsageError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "unknown command") ||
+		strings.HasPrefix(msg, "unknown flag") ||
+		strings.HasPrefix(msg, "unknown shorthand flag")
+}
+
+func accountHint() string {
+	if accountFlag != "" {
+		return accountFlag
+	}
+	return "<account>"
+}
+
+// reportError prints err with a hint to stderr and returns the exit code
+func reportError(cmd *cobra.Command, err error) int {
+	code, hint := classify(err)
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	if code == exitUsage && cmd != nil {
+		hint = fmt.Sprintf("Run '%s --help' for usage.", cmd.CommandPath())
+	}
+	if hint != "" {
+		fmt.Fprintln(os.Stderr, "Hint:", hint)
+	}
+	return code
+}
+
+// markUsageErrors wraps the flag and argument validation of cmd and its
+// subcommands so that their errors classify as usage errors
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validate(c, args); err != nil {
+				return &usageError{err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hashAlgo     string
+	hashBackfill bool
+)
+
+var hashCmd = &cobra.Command{
+	Use:   "hash <az://account//container/blob>",
+	Short: "Compute checksums of blobs, optionally storing them",
+	Long: `Download blobs and print their checksums in the format of md5sum and
+sha256sum. A single blob is listed under the path given, blobs hashed
+with -r under their names relative to the prefix, so the output can be
+checked against a local copy.
+
+With --backfill, the MD5 is stored as the Content-MD5 of blobs that have
+none, which later downloads are checked against; with --algo sha256 the
+SHA-256 is also stored in the blob's "sha256" metadata. A blob that
+changes while it is hashed is not updated.
+
+Examples:
+  # Hash a blob
+  azbutils hash az://myaccount//mycontainer/myfile.txt
+
+  # Check a local copy against the blobs
+  azbutils hash -r --algo sha256 az://myaccount//backups/data > SHA256SUMS
+  (cd data && sha256sum -c ../SHA256SUMS)
+
+  # Store the MD5 of blobs uploaded without one
+  azbutils hash -r --backfill az://myaccount//backups/data
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		algo, err := azbutils.ParseHashAlgorithm(hashAlgo)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		client, err := newLibClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newContext(config.OpTransfer)
+		defer cancel()
+
+		res, err := client.Hash(ctx, args[0], &azbutils.HashOptions{
+			Algorithm: algo,
+			Recursive: recursive,
+			Backfill:  hashBackfill,
+			LeaseID:   leaseID,
+			OnHash: func(h azbutils.BlobHash) {
+				name := args[0]
+				if recursive {
+					name = h.Name
+				}
+				fmt.Printf("%x  %s\n", h.Sum, name)
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		// The checksums on stdout stay machine-readable
+		if hashBackfill {
+			fmt.Fprintf(os.Stderr, "Backfilled %d of %d blob(s).\n", res.Backfilled, len(res.Hashes))
+		}
+		return nil
+	},
+}
+
+func init() {
+	hashCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Hash every blob under the prefix")
+	hashCmd.Flags().StringVar(&hashAlgo, "algo", "md5", "Checksum: md5, sha256 or crc64 (the Azure Storage CRC-64)")
+	hashCmd.Flags().BoolVar(&hashBackfill, "backfill", false, "Store the MD5 in blobs that have none, and the SHA-256 in metadata with --algo sha256")
+	hashCmd.Flags().StringVar(&leaseID, "lease-id", "", "Lease ID required to update leased blobs")
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	leaseID          string
+	leaseProposedID  string
+	leaseDuration    int32
+	leaseBreakPeriod int32
+)
+
+var leaseCmd = &cobra.Command{
+	Use:   "lease",
+	Short: "Manage blob leases",
+	Long: `Acquire, renew, release, break or change leases on blobs.
+
+Leases can be used as distributed locks between jobs. "acquire" prints the lease
+ID so it can be captured by scripts and passed to other commands with --lease-id.
+
+Examples:
+  LEASE=$(azbutils lease acquire az://myaccount//locks/job.lock --duration 60)
+  azbutils lease renew az://myaccount//locks/job.lock --lease-id "$LEASE"
+  azbutils lease release az://myaccount//locks/job.lock --lease-id "$LEASE"
+`,
+}
+
+var leaseAcquireCmd = &cobra.Command{
+	Use:   "acquire <az://account//container/blob>",
+	Short: "Acquire a lease on a blob",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		leaseClient, err := newBlobLeaseClient(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+
+		resp, err := leaseClient.AcquireLease(ctx, leaseDuration, nil)
+		if err != nil {
+			return fmt.Errorf("failed to acquire lease: %w", err)
+		}
+		fmt.Println(*resp.LeaseID)
+		return nil
+	},
+}
+
+var leaseRenewCmd = &cobra.Command{
+	Use:   "renew <az://account//container/blob>",
+	Short: "Renew an existing blob lease",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if leaseID == "" {
+			return usageErrorf("--lease-id is required")
+		}
+		leaseClient, err := newBlobLeaseClient(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+
+		if _, err := leaseClient.RenewLease(ctx, nil); err != nil {
+			return fmt.Errorf("failed to renew lease: %w", err)
+		}
+		fmt.Printf("Renewed lease %s\n", leaseID)
+		return nil
+	},
+}
+
+var leaseReleaseCmd = &cobra.Command{
+	Use:   "release <az://account//container/blob>",
+	Short: "Release a blob lease",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if leaseID == "" {
+			return usageErrorf("--lease-id is required")
+		}
+		leaseClient, err := newBlobLeaseClient(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+
+		if _, err := leaseClient.ReleaseLease(ctx, nil); err != nil {
+			return fmt.Errorf("failed to release lease: %w", err)
+		}
+		fmt.Printf("Released lease %s\n", leaseID)
+		return nil
+	},
+}
+
+var leaseBreakCmd = &cobra.Command{
+	Use:   "break <az://account//container/blob>",
+	Short: "Break a blob lease without knowing its ID",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		leaseClient, err := newBlobLeaseClient(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+
+		opts := &lease.BlobBreakOptions{}
+		if cmd.Flags().Changed("break-period") {
+			opts.BreakPeriod = &leaseBreakPeriod
+		}
+		resp, err := leaseClient.BreakLease(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to break lease: %w", err)
+		}
+		if resp.LeaseTime != nil && *resp.LeaseTime > 0 {
+			fmt.Printf("Lease breaking, available in %d seconds\n", *resp.LeaseTime)
+		} else {
+			fmt.Println("Lease broken")
+		}
+		return nil
+	},
+}
+
+var leaseChangeCmd = &cobra.Command{
+	Use:   "change <az://account//container/blob>",
+	Short: "Change the ID of an active blob lease",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if leaseID == "" || leaseProposedID == "" {
+			return usageErrorf("--lease-id and --proposed-id are required")
+		}
+		leaseClient, err := newBlobLeaseClient(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+
+		resp, err := leaseClient.ChangeLease(ctx, leaseProposedID, nil)
+		if err != nil {
+			return fmt.Errorf("failed to change lease: %w", err)
+		}
+		fmt.Println(*resp.LeaseID)
+		return nil
+	},
+}
+
+// newBlobLeaseClient creates a lease client for the blob at path, bound to --lease-id if set
+func newBlobLeaseClient(path string) (*lease.BlobClient, error) {
+	p, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.SubPath == "" {
+		return nil, usageErrorf("path must point to a blob, not a container")
+	}
+
+	client, err := clientForPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	blobClient := client.ServiceClient().NewContainerClient(p.Container).NewBlobClient(p.SubPath)
+	opts := &lease.BlobClientOptions{}
+	if leaseID != "" {
+		opts.LeaseID = &leaseID
+	}
+	return lease.NewBlobClient(blobClient, opts)
+}
+
+func init() {
+	leaseCmd.PersistentFlags().StringVar(&leaseID, "lease-id", "", "Lease ID (proposed ID for acquire, current ID otherwise)")
+	leaseAcquireCmd.Flags().Int32Var(&leaseDuration, "duration", -1, "Lease duration in seconds (15-60, or -1 for infinite)")
+	leaseBreakCmd.Flags().Int32Var(&leaseBreakPeriod, "break-period", 0, "Seconds (0-60) the lease should continue before it is broken")
+	leaseChangeCmd.Flags().StringVar(&leaseProposedID, "proposed-id", "", "New lease ID")
+
+	leaseCmd.AddCommand(leaseAcquireCmd)
+	leaseCmd.AddCommand(leaseRenewCmd)
+	leaseCmd.AddCommand(leaseReleaseCmd)
+	leaseCmd.AddCommand(leaseBreakCmd)
+	leaseCmd.AddCommand(leaseChangeCmd)
+}
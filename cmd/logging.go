@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verbose   bool
+	debug     bool
+	traceFile string
+)
+
+func addLoggingFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.BoolVarP(&verbose, "verbose", "v", false, "Log every HTTP request with its status and duration to stderr")
+	flags.BoolVar(&debug, "debug", false, "Also log headers, error bodies, retries and authentication (secrets redacted)")
+	flags.StringVar(&traceFile, "trace-file", "", "Write all HTTP exchanges to a HAR-like JSON file")
+}
+
+// setupLogging installs the stderr logger matching --verbose and --debug and
+// starts the --trace-file recording
+func setupLogging() error {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelInfo
+	}
+	if debug {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
+	azure.SetLogger(logger)
+
+	if traceFile != "" {
+		if err := azure.StartTrace(traceFile, version); err != nil {
+			return err
+		}
+		slog.Debug("tracing HTTP exchanges", "file", traceFile)
+	}
+	return nil
+}
+
+// finishLogging writes the trace file, if any
+func finishLogging() {
+	if err := azure.StopTrace(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -1,22 +1,19 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
-	"github.com/orionnectar/go-azbutils/internal/azpath"
-	"github.com/orionnectar/go-azbutils/internal/azure"
 	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	recursive bool
-	fullPath  bool
-	pretty    bool
+	recursive    bool
+	fullPath     bool
+	pretty       bool
+	listVersions bool
+	listDeleted  bool
 )
 
 var lsCmd = &cobra.Command{
@@ -24,60 +21,32 @@ var lsCmd = &cobra.Command{
 	Short: "List blobs in a container or virtual directory",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		p, err := azpath.Parse(args[0])
+		p, err := parsePath(args[0])
 		if err != nil {
 			return err
 		}
-
-		cfg, err := config.Load()
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
-		}
-
-		acctCfg := cfg.Accounts[p.Account]
-		if acctCfg == nil {
-			return fmt.Errorf("no account config found for '%s'", p.Account)
-		}
-
-		client, err := azure.NewClientFromConfigAccount(acctCfg)
+		client, err := newLibClient()
 		if err != nil {
-			return fmt.Errorf("failed to create Azure client: %w", err)
+			return err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := newContext(config.OpList)
 		defer cancel()
 
-		containerClient := client.ServiceClient().NewContainerClient(p.Container)
-
+		// Entries are printed as the pages arrive, so large containers
+		// neither wait for the whole listing nor hold it in memory
 		fmt.Printf("Listing blobs in '%s' (account: %s):\n", p.Container, p.Account)
-
-		if recursive {
-			pager := containerClient.NewListBlobsFlatPager(&azblob.ListBlobsFlatOptions{Prefix: &p.SubPath})
-			for pager.More() {
-				page, err := pager.NextPage(ctx)
-				if err != nil {
-					return fmt.Errorf("list error: %w", err)
-				}
-				for _, blob := range page.Segment.BlobItems {
-					printBlob(p, *blob.Name, false)
-				}
-			}
-		} else {
-			pager := containerClient.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: &p.SubPath})
-			for pager.More() {
-				page, err := pager.NextPage(ctx)
-				if err != nil {
-					return fmt.Errorf("list error: %w", err)
-				}
-				for _, prefix := range page.Segment.BlobPrefixes {
-					printBlob(p, *prefix.Name, true)
-				}
-				for _, blob := range page.Segment.BlobItems {
-					printBlob(p, *blob.Name, false)
-				}
-			}
+		err = client.ListFunc(ctx, args[0], &azbutils.ListOptions{
+			Recursive: recursive,
+			Versions:  listVersions,
+			Deleted:   listDeleted,
+		}, func(entry azbutils.Entry) error {
+			printBlobItem(entry)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-
 		return nil
 	},
 }
@@ -86,17 +55,35 @@ func init() {
 	lsCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Recursively list all blobs")
 	lsCmd.Flags().BoolVar(&fullPath, "full-path", false, "Show full blob path (az:// or https)")
 	lsCmd.Flags().BoolVar(&pretty, "pretty", false, "Show pretty icons for files and directories")
+	lsCmd.Flags().BoolVar(&listVersions, "versions", false, "List all blob versions with their version IDs")
+	lsCmd.Flags().BoolVar(&listDeleted, "deleted", false, "Include soft-deleted blobs and their remaining retention days")
 }
 
-func printBlob(p *azpath.BlobPath, name string, isDir bool) {
-	var output string
-
+// printBlobItem prints a listed blob, annotated with version and soft-delete details
+func printBlobItem(entry azbutils.Entry) {
+	output := entry.Name
 	if fullPath {
-		output = p.BuildFull(name)
-	} else {
-		output = name
+		output = entry.URL
+	}
+	versioned := listVersions && entry.VersionID != ""
+	if versioned && !fullPath {
+		output = fmt.Sprintf("%s\t%s", entry.Name, entry.VersionID)
 	}
+	if versioned && entry.IsCurrentVersion {
+		output += "\t(current)"
+	}
+	if entry.Deleted {
+		output += "\t(deleted"
+		if entry.RemainingRetentionDays > 0 {
+			output += fmt.Sprintf(", %d days left", entry.RemainingRetentionDays)
+		}
+		output += ")"
+	}
+
+	printEntry(output, entry.IsPrefix)
+}
 
+func printEntry(output string, isDir bool) {
 	if pretty {
 		if isDir {
 			fmt.Printf(" 📁 %s\n", output)
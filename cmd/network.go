@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// Network flags; set ones override the network section of the config
+var (
+	maxRetries       int32
+	retryDelay       string
+	maxRetryDelay    string
+	retryStatusCodes []int
+	tryTimeout       string
+	timeoutFlag      string
+	proxyFlag        string
+	caBundle         string
+	disableHTTP2     bool
+)
+
+// networkSettings holds the effective network settings once the root
+// command's pre-run has applied them
+var networkSettings *config.NetworkConfig
+
+func addNetworkFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.Int32Var(&maxRetries, "max-retries", 0, "Retries after a failed request (-1 disables retries; default 3)")
+	flags.StringVar(&retryDelay, "retry-delay", "", "Initial delay between retries, e.g. 4s")
+	flags.StringVar(&maxRetryDelay, "max-retry-delay", "", "Maximum delay between retries, e.g. 60s")
+	flags.IntSliceVar(&retryStatusCodes, "retry-status-codes", nil, "HTTP status codes to retry (replaces the defaults)")
+	flags.StringVar(&tryTimeout, "try-timeout", "", "Timeout of a single HTTP request attempt")
+	flags.StringVar(&timeoutFlag, "timeout", "", "Timeout of the whole operation, e.g. 10m (0 for none)")
+	flags.StringVar(&proxyFlag, "proxy", "", "Proxy URL, or 'direct' to ignore HTTPS_PROXY/HTTP_PROXY")
+	flags.StringVar(&caBundle, "ca-bundle", "", "PEM file of additional trusted CA certificates")
+	flags.BoolVar(&disableHTTP2, "disable-http2", false, "Use HTTP/1.1 only")
+}
+
+// networkOverride collects the network flags given on the command line
+func networkOverride() *config.NetworkConfig {
+	n := &config.NetworkConfig{
+		MaxRetries:       maxRetries,
+		RetryDelay:       retryDelay,
+		MaxRetryDelay:    maxRetryDelay,
+		RetryStatusCodes: retryStatusCodes,
+		TryTimeout:       tryTimeout,
+		Proxy:            proxyFlag,
+		CABundle:         caBundle,
+	}
+	n.Timeouts = config.Timeouts{List: timeoutFlag, Transfer: timeoutFlag, Default: timeoutFlag}
+	n.HTTP2.Disable = disableHTTP2
+	return n
+}
+
+// configureNetwork applies the effective network settings to the clients
+// created by this process
+func configureNetwork(cmd *cobra.Command) error {
+	config.SetNetworkOverride(networkOverride())
+	if cmd.Parent() == configCmd {
+		// config validate and edit must work while the settings are broken
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		// reported by the command itself
+		return nil
+	}
+	if err := azure.Configure(cfg.Network); err != nil {
+		return fmt.Errorf("invalid network settings: %w", err)
+	}
+	networkSettings = cfg.Network
+	slog.Debug("loaded config", "accounts", len(cfg.Accounts), "project", config.ProjectPath())
+	return nil
+}
+
+// newContext returns a context bounded by the timeout of operation class op
+// and canceled by the first interrupt
+func newContext(op string) (context.Context, context.CancelFunc) {
+	return withTimeout(rootContext(), op)
+}
+
+// newDrainContext is newContext for transfers that finish on the first
+// interrupt: the context outlives it, and interrupt is closed when it arrives
+func newDrainContext(op string) (ctx context.Context, cancel context.CancelFunc, interrupt <-chan struct{}) {
+	ctx, cancel = withTimeout(context.WithoutCancel(rootContext()), op)
+	return ctx, cancel, rootContext().Done()
+}
+
+func withTimeout(parent context.Context, op string) (context.Context, context.CancelFunc) {
+	// validated by configureNetwork
+	timeout, _ := networkSettings.Timeout(op)
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// testConnection runs azure.TestConnection within the default timeout
+func testConnection(client *azblob.Client) error {
+	ctx, cancel := newContext(config.OpDefault)
+	defer cancel()
+	return azure.TestConnection(ctx, client)
+}
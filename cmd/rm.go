@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <az://account//container/path>",
+	Short: "Delete a blob or all blobs under a prefix",
+	Long: `Delete a single blob, or every blob under a prefix with -r.
+
+Examples:
+  # Delete a single blob
+  azbutils rm az://myaccount//mycontainer/myfile.txt
+
+  # Delete a leased blob
+  azbutils rm az://myaccount//mycontainer/myfile.txt --lease-id <id>
+
+  # Preview a recursive delete
+  azbutils rm az://myaccount//mycontainer/logs/ -r --dry-run
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := parsePath(args[0])
+		if err != nil {
+			return err
+		}
+		if !recursive && p.SubPath == "" {
+			return usageErrorf("path must point to a blob. Use -r or --recursive to delete a whole container or prefix")
+		}
+		if p.VersionID != "" {
+			return usageErrorf("rm deletes current blobs, but the path names version %s", p.VersionID)
+		}
+
+		st, err := storeForPath(p)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newContext(config.OpList)
+		defer cancel()
+
+		if !recursive {
+			return deleteBlob(ctx, st, p, p.SubPath)
+		}
+
+		var names []string
+		err = st.List(ctx, p.Container, store.ListOptions{Prefix: p.DirPrefix()}, func(obj store.Object) error {
+			names = append(names, obj.Name)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("list error: %w", err)
+		}
+		var failed []error
+		for i, name := range names {
+			if ctx.Err() != nil {
+				return fmt.Errorf("stopped after %d of %d blob(s): %w", i, len(names), ctx.Err())
+			}
+			if err := deleteBlob(ctx, st, p, name); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = append(failed, err)
+			}
+		}
+
+		if dryRun {
+			fmt.Printf("[dry-run] %d blob(s) would be deleted.\n", len(names))
+		} else {
+			fmt.Printf("Deleted %d blob(s).\n", len(names)-len(failed))
+		}
+		return bulkResult("delete", len(names), failed)
+	},
+}
+
+func deleteBlob(ctx context.Context, st store.Store, p *azpath.BlobPath, name string) error {
+	if dryRun {
+		fmt.Printf("[dry-run] Would delete %s\n", p.BuildFull(name))
+		return nil
+	}
+
+	fmt.Printf("Deleting %s\n", p.BuildFull(name))
+	if err := st.Delete(ctx, p.Container, name, &store.DeleteOptions{LeaseID: leaseID}); err != nil {
+		return fmt.Errorf("failed to delete '%s': %w", name, err)
+	}
+	return nil
+}
+
+func init() {
+	rmCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Delete all blobs under the path")
+	rmCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Preview delete actions without performing them")
+	rmCmd.Flags().StringVar(&leaseID, "lease-id", "", "Lease ID required to delete a leased blob")
+}
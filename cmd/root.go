@@ -4,33 +4,76 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/secret"
 	"github.com/spf13/cobra"
 )
 
 var (
-	rootCmd *cobra.Command
-	version string
+	rootCmd     *cobra.Command
+	version     string
+	configFile  string
+	accountFlag string
 )
 
 func Execute(ver string) {
 	version = ver
+	secret.PassphrasePrompt = promptPassphrase
+	config.MigrateSecret = migrateSecret
 
 	rootCmd = &cobra.Command{
 		Use:   "azbutils",
 		Short: "gsutil-like CLI for Azure Blob Storage",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			config.SetPath(configFile)
+			if err := setupLogging(); err != nil {
+				return err
+			}
+			return configureNetwork(cmd)
+		},
 	}
+	rootCmd.PersistentFlags().StringVar(&accountFlag, "account", "", "Account for short paths (az://<container>/<path> or <container>/<path>)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", fmt.Sprintf("Config file (default $%s or ~/.config/azbutils/config.json)", config.EnvPath))
+	addNetworkFlags(rootCmd)
+	addLoggingFlags(rootCmd)
 
 	// Add subcommands
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(lsCmd)
 	rootCmd.AddCommand(cpCmd)
 	rootCmd.AddCommand(catCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(hashCmd)
+	rootCmd.AddCommand(rmCmd)
+	rootCmd.AddCommand(undeleteCmd)
+	rootCmd.AddCommand(leaseCmd)
+	rootCmd.AddCommand(sasCmd)
+	rootCmd.AddCommand(shareCmd)
 	rootCmd.AddCommand(connectCmd)
 	rootCmd.AddCommand(accountCmd)
+	rootCmd.AddCommand(aliasCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(completionCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	markUsageErrors(rootCmd)
+
+	ctx, stop := signalContext()
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	stop()
+	finishLogging()
+	if err != nil {
+		os.Exit(reportError(cmd, err))
 	}
 }
+
+// promptPassphrase asks for the passphrase of the encrypted secrets file
+func promptPassphrase() (string, error) {
+	var passphrase string
+	err := survey.AskOne(&survey.Password{
+		Message: fmt.Sprintf("Secrets file passphrase (or set %s):", secret.PassphraseEnv),
+	}, &passphrase, survey.WithValidator(survey.Required))
+	return passphrase, err
+}
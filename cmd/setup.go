@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/secret"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setupAuth           string
+	setupServiceURL     string
+	setupStorageAccount string
+	setupTenantID       string
+	setupClientID       string
+	setupCertificate    string
+	setupTokenFile      string
+	secretStdin         bool
+	nonInteractive      bool
+	skipTest            bool
+)
+
+// addSetupFlags registers the flags of the account setup wizard on cmd
+func addSetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&setupAuth, "auth", "", "Auth method: "+strings.Join(azure.AuthMethods, ", "))
+	cmd.Flags().StringVar(&setupServiceURL, "service-url", "", "Blob service URL (default derived from the account name)")
+	cmd.Flags().StringVar(&setupStorageAccount, "storage-account", "", "Storage account name, if different from the account name")
+	cmd.Flags().StringVar(&setupTenantID, "tenant-id", "", "Entra ID tenant ID")
+	cmd.Flags().StringVar(&setupClientID, "client-id", "", "Entra ID client ID")
+	cmd.Flags().StringVar(&setupCertificate, "certificate", "", "Certificate path for service-principal-cert")
+	cmd.Flags().StringVar(&setupTokenFile, "token-file", "", "Federated token file for workload-identity")
+	cmd.Flags().BoolVar(&secretStdin, "secret-stdin", false, "Read the account secret (key, connection string, SAS URL, client secret) from stdin")
+	cmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Do not prompt; take all settings from flags")
+	cmd.Flags().BoolVar(&skipTest, "skip-test", false, "Save the account without testing the connection")
+	cmd.Flags().StringVar(&secretBackend, "secret-store", "", "Secret store backend: keychain, secret-service, file or env")
+	cmd.Flags().StringVar(&cloudName, "cloud", "", "Azure cloud: public, usgovernment or china")
+	cmd.Flags().StringVar(&endpointSuffix, "endpoint-suffix", "", "Custom storage endpoint suffix (e.g. core.windows.net)")
+}
+
+// runSetupWizard builds the settings of account name, starting from existing
+// when it is set. Values come from flags, then prompts unless --non-interactive.
+// The settings are validated and tested before any secret is stored. It
+// reports whether the connection test succeeded.
+func runSetupWizard(cmd *cobra.Command, name string, existing *config.AccountConfig) (*config.AccountConfig, bool, error) {
+	var acct *config.AccountConfig
+	if existing != nil {
+		edited := *existing
+		acct = &edited
+	} else {
+		var err error
+		if acct, err = newAccountConfig(name); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := applySetupFlags(cmd, acct); err != nil {
+		return nil, false, err
+	}
+
+	var value string
+	if secretStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read secret from stdin: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+
+	if nonInteractive {
+		if acct.AuthMethod == "" {
+			return nil, false, fmt.Errorf("--auth is required with --non-interactive")
+		}
+	} else {
+		var err error
+		if value, err = promptAccountSettings(name, acct, value); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := azure.ValidateAccount(acct, value); err != nil {
+		return nil, false, err
+	}
+
+	connected, err := testBeforeSave(acct, value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if value != "" {
+		ref, err := storeAccountSecret(name, acct, value)
+		if err != nil {
+			return nil, false, err
+		}
+		acct.SecretRef = ref
+	}
+	return acct, connected, nil
+}
+
+// applySetupFlags copies the wizard flags that were set onto acct
+func applySetupFlags(cmd *cobra.Command, acct *config.AccountConfig) error {
+	flags := cmd.Flags()
+	if flags.Changed("cloud") || flags.Changed("endpoint-suffix") {
+		if err := azure.ValidateCloud(cloudName); err != nil {
+			return err
+		}
+		acct.Cloud = cloudName
+		acct.EndpointSuffix = endpointSuffix
+		acct.ServiceURL = azure.DefaultServiceURL(acct.AccountName, acct)
+	}
+	if flags.Changed("auth") {
+		acct.AuthMethod = setupAuth
+	}
+	if flags.Changed("storage-account") {
+		acct.AccountName = setupStorageAccount
+		acct.ServiceURL = azure.DefaultServiceURL(setupStorageAccount, acct)
+	}
+	if flags.Changed("service-url") {
+		acct.ServiceURL = setupServiceURL
+	}
+	if flags.Changed("tenant-id") {
+		acct.TenantID = setupTenantID
+	}
+	if flags.Changed("client-id") {
+		acct.ClientID = setupClientID
+	}
+	if flags.Changed("certificate") {
+		acct.CertificatePath = setupCertificate
+	}
+	if flags.Changed("token-file") {
+		acct.TokenFilePath = setupTokenFile
+	}
+	return nil
+}
+
+// testBeforeSave checks that acct can list containers unless --skip-test is
+// set. Interactive runs may choose to save a failing account anyway.
+func testBeforeSave(acct *config.AccountConfig, value string) (bool, error) {
+	if skipTest {
+		return false, nil
+	}
+
+	fmt.Println("Testing connection...")
+	client, err := azure.NewClientWithSecret(acct, value)
+	if err == nil {
+		err = testConnection(client)
+	}
+	if err == nil {
+		return true, nil
+	}
+
+	if nonInteractive {
+		return false, fmt.Errorf("connection test failed: %w (use --skip-test to save anyway)", err)
+	}
+	fmt.Printf("Connection test failed: %v\n", err)
+	save := false
+	if err := survey.AskOne(&survey.Confirm{Message: "Save the account anyway?"}, &save); err != nil {
+		return false, err
+	}
+	if !save {
+		return false, fmt.Errorf("account not saved")
+	}
+	return false, nil
+}
+
+// newAccountConfig starts an account in the cloud selected by --cloud / --endpoint-suffix
+func newAccountConfig(name string) (*config.AccountConfig, error) {
+	if err := azure.ValidateCloud(cloudName); err != nil {
+		return nil, err
+	}
+	acct := &config.AccountConfig{
+		AccountName:    name,
+		Cloud:          cloudName,
+		EndpointSuffix: endpointSuffix,
+	}
+	acct.ServiceURL = azure.DefaultServiceURL(name, acct)
+	return acct, nil
+}
+
+// promptAccountSettings asks for the auth method and its settings, using the
+// current values of acct as defaults. It returns the secret entered, or value
+// when a secret was already given.
+func promptAccountSettings(name string, acct *config.AccountConfig, value string) (string, error) {
+	if err := survey.AskOne(&survey.Select{
+		Message: "Choose auth method:",
+		Options: azure.AuthMethods,
+		Default: acct.AuthMethod,
+	}, &acct.AuthMethod); err != nil {
+		return "", err
+	}
+
+	if acct.AuthMethod != "sas" {
+		if err := survey.AskOne(&survey.Input{Message: "Service URL:", Default: acct.ServiceURL}, &acct.ServiceURL,
+			survey.WithValidator(validateWith(azure.ValidateServiceURL, false))); err != nil {
+			return "", err
+		}
+	}
+	if acct.AuthMethod == "shared-key" {
+		accountName := acct.AccountName
+		if accountName == "" {
+			accountName = name
+		}
+		if err := survey.AskOne(&survey.Input{Message: "Account name:", Default: accountName}, &acct.AccountName,
+			survey.WithValidator(validateWith(azure.ValidateAccountName, false))); err != nil {
+			return "", err
+		}
+	}
+
+	if value == "" {
+		var err error
+		if value, err = promptSecret(acct); err != nil {
+			return "", err
+		}
+	}
+	return value, promptIdentity(acct)
+}
+
+// promptSecret asks for the secret of the account's auth method, if it has one
+func promptSecret(acct *config.AccountConfig) (string, error) {
+	keep := acct.SecretRef != ""
+	suffix := ""
+	if keep {
+		suffix = " (leave empty to keep current)"
+	}
+
+	var prompt *survey.Password
+	var check func(string) error
+	switch acct.AuthMethod {
+	case "connection-string":
+		prompt = &survey.Password{Message: "Connection string" + suffix + ":"}
+		check = func(s string) error {
+			_, err := azure.ParseConnectionString(s)
+			return err
+		}
+	case "shared-key":
+		prompt = &survey.Password{Message: "Account key" + suffix + ":"}
+		check = azure.ValidateAccountKey
+	case "sas":
+		prompt = &survey.Password{Message: "SAS URL (with ?sig=...)" + suffix + ":"}
+		check = azure.ValidateSASURL
+	case "service-principal-secret":
+		prompt = &survey.Password{Message: "Client secret" + suffix + ":"}
+		check = func(string) error { return nil }
+	case "service-principal-cert":
+		prompt = &survey.Password{Message: "Certificate password (leave empty if none):"}
+		keep = true
+		check = func(string) error { return nil }
+	default:
+		return "", nil
+	}
+
+	var value string
+	err := survey.AskOne(prompt, &value, survey.WithValidator(validateWith(check, keep)))
+	return value, err
+}
+
+// promptIdentity asks for the Entra ID settings the account's auth method uses
+func promptIdentity(acct *config.AccountConfig) error {
+	var qs []*survey.Question
+	input := func(name, message, def string) {
+		qs = append(qs, &survey.Question{Name: name, Prompt: &survey.Input{Message: message, Default: def}})
+	}
+
+	switch acct.AuthMethod {
+	case "service-principal-secret", "service-principal-cert":
+		qs = append(qs,
+			&survey.Question{Name: "TenantID", Prompt: &survey.Input{Message: "Tenant ID:", Default: acct.TenantID}, Validate: survey.Required},
+			&survey.Question{Name: "ClientID", Prompt: &survey.Input{Message: "Client ID:", Default: acct.ClientID}, Validate: survey.Required},
+		)
+		if acct.AuthMethod == "service-principal-cert" {
+			qs = append(qs, &survey.Question{Name: "CertificatePath", Prompt: &survey.Input{Message: "Certificate path (PEM or PKCS#12):", Default: acct.CertificatePath}, Validate: survey.Required})
+		}
+	case "managed-identity":
+		input("ClientID", "User-assigned client ID (leave empty for system-assigned):", acct.ClientID)
+	case "workload-identity":
+		input("TenantID", "Tenant ID (leave empty to use AZURE_TENANT_ID):", acct.TenantID)
+		input("ClientID", "Client ID (leave empty to use AZURE_CLIENT_ID):", acct.ClientID)
+		input("TokenFilePath", "Token file (leave empty to use AZURE_FEDERATED_TOKEN_FILE):", acct.TokenFilePath)
+	case "device-code", "interactive-browser":
+		input("TenantID", "Tenant ID (leave empty for default):", acct.TenantID)
+		input("ClientID", "Client ID (leave empty for default):", acct.ClientID)
+	case "az-login", "azure-cli":
+		input("TenantID", "Tenant ID (leave empty for default):", acct.TenantID)
+	}
+
+	if len(qs) == 0 {
+		return nil
+	}
+	return survey.Ask(qs, acct)
+}
+
+// validateWith adapts a validation func to survey; optional allows empty answers
+func validateWith(check func(string) error, optional bool) survey.Validator {
+	return func(ans interface{}) error {
+		s, _ := ans.(string)
+		if s == "" {
+			if optional {
+				return nil
+			}
+			return fmt.Errorf("value is required")
+		}
+		return check(s)
+	}
+}
+
+// storeAccountSecret saves an account secret in the chosen secret store and
+// returns the reference to keep in the config. Secrets never go to config.json.
+func storeAccountSecret(name string, acct *config.AccountConfig, value string) (string, error) {
+	backend := secretBackend
+	if backend == "" {
+		backends := secret.Backends()
+		backend = backends[0]
+		if !nonInteractive {
+			if err := survey.AskOne(&survey.Select{
+				Message: "Where should the secret be stored?",
+				Options: backends,
+				Default: backends[0],
+			}, &backend); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if backend == secret.BackendEnv {
+		envName := azure.SecretEnvName(acct, azure.SecretEnvSuffix(acct.AuthMethod))
+		fmt.Printf("Secret not stored. Export it before use:\n  export %s=...\n", envName)
+		return secret.Ref(secret.BackendEnv, envName), nil
+	}
+
+	store, err := secret.Open(backend)
+	if err != nil {
+		return "", err
+	}
+	if err := store.Set(name, value); err != nil {
+		return "", fmt.Errorf("failed to store secret: %w", err)
+	}
+	return secret.Ref(backend, name), nil
+}
+
+// migrateSecret moves a plaintext secret found in an old config file into the
+// first secret store that accepts it
+func migrateSecret(name, value string) (string, error) {
+	var errs []error
+	for _, backend := range secret.Backends() {
+		if backend == secret.BackendEnv {
+			continue
+		}
+		store, err := secret.Open(backend)
+		if err == nil {
+			err = store.Set(name, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend, err))
+			continue
+		}
+		return secret.Ref(backend, name), nil
+	}
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/share"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shareExpiry string
+	shareQR     bool
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share <local-file> <az://account//container/path>",
+	Short: "Upload a file and print a read-only link to it",
+	Long: `Upload a local file and print a read-only SAS URL for it.
+
+Each link is backed by its own stored access policy on the container, so it can
+be revoked later with "azbutils share revoke". Shares are recorded, without
+their links, in a local ledger in the config directory. Requires a shared-key or
+connection-string account; a container can hold at most 5 stored access
+policies.
+
+Examples:
+  azbutils share ./report.pdf az://myaccount//public/reports/ --expiry 24h
+  azbutils share ./report.pdf az://myaccount//public/report.pdf --qr
+  azbutils share list
+  azbutils share revoke 1a2b3c4d
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src := args[0]
+		info, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("failed to access source: %w", err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("'%s' is a directory; only single files can be shared", src)
+		}
+
+		p, err := parsePath(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid destination path: %w", err)
+		}
+		if p.SubPath == "" || strings.HasSuffix(p.SubPath, "/") {
+			p.SubPath += filepath.Base(src)
+		}
+
+		expiry, err := parseExpiry(shareExpiry)
+		if err != nil {
+			return err
+		}
+
+		acctCfg, err := accountForPath(p)
+		if err != nil {
+			return err
+		}
+		if acctCfg.AuthMethod != "shared-key" && acctCfg.AuthMethod != "connection-string" && acctCfg.AuthMethod != "emulator" {
+			return fmt.Errorf("share requires a shared-key or connection-string account (stored access policies cannot back user delegation SAS)")
+		}
+		client, err := clientForPath(p)
+		if err != nil {
+			return err
+		}
+		lib, err := newLibClient()
+		if err != nil {
+			return err
+		}
+
+		id, err := newShareID()
+		if err != nil {
+			return err
+		}
+		policy := azure.SharePolicyPrefix + id
+
+		// The policy comes first, so a container without room for one is
+		// found before anything is uploaded
+		now := time.Now().UTC()
+		containerClient := client.ServiceClient().NewContainerClient(p.Container)
+		policyCtx, cancelPolicy := newContext(config.OpDefault)
+		defer cancelPolicy()
+		if err := azure.AddAccessPolicy(policyCtx, containerClient, policy, "r", now.Add(-5*time.Minute), expiry); err != nil {
+			return err
+		}
+
+		dst := fmt.Sprintf("az://%s//%s/%s", p.Account, p.Container, p.SubPath)
+		uploadCtx, cancelUpload := newContext(config.OpTransfer)
+		defer cancelUpload()
+		if _, err := lib.Copy(uploadCtx, src, dst, &azbutils.CopyOptions{OnTransfer: printTransfer}); err != nil {
+			removeSharePolicy(containerClient, policy)
+			return err
+		}
+
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+		url, err := azure.GenerateSASURL(ctx, acctCfg, client, azure.SASOptions{
+			Container: p.Container,
+			BlobName:  p.SubPath,
+			Protocol:  azure.SASProtocol(client.URL()),
+			Policy:    policy,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = share.Update(func(ledger *share.Ledger) error {
+			ledger.Shares = append(ledger.Shares, &share.Entry{
+				ID:        id,
+				Account:   p.Account,
+				Container: p.Container,
+				Blob:      p.SubPath,
+				Policy:    policy,
+				Created:   now,
+				Expiry:    expiry,
+			})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save share ledger: %w", err)
+		}
+
+		fmt.Printf("Shared %s (id: %s, expires %s)\n", p.BuildFull(p.SubPath), id, expiry.Local().Format(time.RFC1123))
+		fmt.Println(url)
+		if shareQR {
+			qr, err := qrcode.New(url, qrcode.Low)
+			if err != nil {
+				return fmt.Errorf("failed to render QR code: %w", err)
+			}
+			fmt.Print(qr.ToSmallString(false))
+		}
+		return nil
+	},
+}
+
+var shareListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List shared links recorded in the local ledger",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ledger, err := share.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load share ledger: %w", err)
+		}
+		if len(ledger.Shares) == 0 {
+			fmt.Println("No shared links.")
+			return nil
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tEXPIRES\tPATH")
+		for _, e := range ledger.Shares {
+			path := fmt.Sprintf("az://%s//%s/%s", e.Account, e.Container, e.Blob)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.ID, e.Status(now), e.Expiry.Local().Format(time.DateTime), path)
+		}
+		return w.Flush()
+	},
+}
+
+var shareRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Invalidate a shared link by removing its stored access policy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ledger, err := share.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load share ledger: %w", err)
+		}
+		entry := ledger.Find(args[0])
+		if entry == nil {
+			return fmt.Errorf("share '%s' not found", args[0])
+		}
+		if entry.Revoked != nil {
+			fmt.Printf("Share '%s' is already revoked\n", entry.ID)
+			return nil
+		}
+
+		p := &azpath.BlobPath{Account: entry.Account, Container: entry.Container, SubPath: entry.Blob, Type: "az"}
+		client, err := clientForPath(p)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newContext(config.OpDefault)
+		defer cancel()
+
+		containerClient := client.ServiceClient().NewContainerClient(entry.Container)
+		if err := azure.RemoveAccessPolicy(ctx, containerClient, entry.Policy); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		err = share.Update(func(ledger *share.Ledger) error {
+			if e := ledger.Find(entry.ID); e != nil {
+				e.Revoked = &now
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save share ledger: %w", err)
+		}
+		fmt.Printf("✅ Revoked share '%s' (%s)\n", entry.ID, p.BuildFull(entry.Blob))
+		return nil
+	},
+}
+
+// removeSharePolicy removes the policy of a share whose upload failed, also
+// after an interrupt
+func removeSharePolicy(containerClient *container.Client, policy string) {
+	ctx, cancel, _ := newDrainContext(config.OpDefault)
+	defer cancel()
+	if err := azure.RemoveAccessPolicy(ctx, containerClient, policy); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not remove access policy %s: %v\n", policy, err)
+	}
+}
+
+func newShareID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate share ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func init() {
+	shareCmd.Flags().StringVar(&shareExpiry, "expiry", "24h", "Link expiry as a duration (24h, 7d) or RFC 3339 time")
+	shareCmd.Flags().BoolVar(&shareQR, "qr", false, "Also print the link as a QR code")
+
+	shareCmd.AddCommand(shareListCmd)
+	shareCmd.AddCommand(shareRevokeCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exitInterrupted follows the shell convention of 128 + SIGINT
+const exitInterrupted = 130
+
+// errInterrupted is the cause of the root context's cancellation
+var errInterrupted = errors.New("interrupted")
+
+// signalContext returns the root context, canceled by the first SIGINT or
+// SIGTERM. Transfers drain on that signal; a second one exits immediately.
+func signalContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(os.Stderr, "\nReceived %s: stopping after the transfers in flight. Press Ctrl-C again to exit immediately.\n", sig)
+			cancel(errInterrupted)
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "Exiting immediately.")
+			finishLogging()
+			os.Exit(exitInterrupted)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel(nil)
+	}
+}
+
+// rootContext returns the context of the running command
+func rootContext() context.Context {
+	if rootCmd != nil && rootCmd.Context() != nil {
+		return rootCmd.Context()
+	}
+	return context.Background()
+}
+
+// isInterrupted reports whether err stems from the first signal
+func isInterrupted(err error) bool {
+	return errors.Is(err, errInterrupted) ||
+		(errors.Is(err, context.Canceled) && errors.Is(context.Cause(rootContext()), errInterrupted))
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var undeleteCmd = &cobra.Command{
+	Use:   "undelete <az://account//container/path>",
+	Short: "Restore soft-deleted blobs",
+	Long: `Restore a soft-deleted blob, or every soft-deleted blob under a prefix with -r.
+
+Soft delete must be enabled on the storage account and the blobs must still be
+within their retention period (see "azbutils ls --deleted").
+
+Examples:
+  # Restore a single blob
+  azbutils undelete az://myaccount//mycontainer/myfile.txt
+
+  # Restore everything under a prefix
+  azbutils undelete az://myaccount//mycontainer/logs/ -r
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := parsePath(args[0])
+		if err != nil {
+			return err
+		}
+		if !recursive && p.SubPath == "" {
+			return usageErrorf("path must point to a blob. Use -r or --recursive to restore a whole container or prefix")
+		}
+
+		client, err := clientForPath(p)
+		if err != nil {
+			return err
+		}
+		containerClient := client.ServiceClient().NewContainerClient(p.Container)
+
+		ctx, cancel := newContext(config.OpList)
+		defer cancel()
+
+		if !recursive {
+			return undeleteBlob(ctx, containerClient, p, p.SubPath)
+		}
+
+		restored, total := 0, 0
+		var failed []error
+		prefix := p.DirPrefix()
+		pager := containerClient.NewListBlobsFlatPager(&azblob.ListBlobsFlatOptions{
+			Prefix:  &prefix,
+			Include: container.ListBlobsInclude{Deleted: true},
+		})
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("list error: %w", err)
+			}
+			for _, blob := range page.Segment.BlobItems {
+				if blob.Deleted == nil || !*blob.Deleted {
+					continue
+				}
+				total++
+				if err := undeleteBlob(ctx, containerClient, p, *blob.Name); err != nil {
+					if ctx.Err() != nil {
+						return fmt.Errorf("stopped after %d blob(s): %w", restored, ctx.Err())
+					}
+					fmt.Fprintln(os.Stderr, err)
+					failed = append(failed, err)
+					continue
+				}
+				restored++
+			}
+		}
+
+		if dryRun {
+			fmt.Printf("[dry-run] %d blob(s) would be restored.\n", restored)
+		} else {
+			fmt.Printf("Restored %d blob(s).\n", restored)
+		}
+		return bulkResult("restore", total, failed)
+	},
+}
+
+func undeleteBlob(ctx context.Context, containerClient *container.Client, p *azpath.BlobPath, name string) error {
+	if dryRun {
+		fmt.Printf("[dry-run] Would restore %s\n", p.BuildFull(name))
+		return nil
+	}
+
+	fmt.Printf("Restoring %s\n", p.BuildFull(name))
+	if _, err := containerClient.NewBlobClient(name).Undelete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to restore '%s': %w", name, err)
+	}
+	return nil
+}
+
+func init() {
+	undeleteCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Restore all soft-deleted blobs under the path")
+	undeleteCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Preview restore actions without performing them")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/pkg/azbutils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkMD5       string
+	verifyQuiet    bool
+	verifyDownload bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <local> <remote>",
+	Short: "Compare a local file or directory with blobs by MD5",
+	Long: `Compare a local file or directory with a blob or prefix, file by file.
+Blobs are compared by their stored Content-MD5; blobs without one, and
+all blobs with --download, are downloaded and hashed. Every difference is
+listed, and the command fails if there is any.
+
+Examples:
+  # Check an upload
+  azbutils verify ./data az://myaccount//backups/data
+
+  # Check the stored data itself, not just the stored MD5s
+  azbutils verify ./data az://myaccount//backups/data --download
+
+  # Check a single file
+  azbutils verify ./report.pdf az://myaccount//docs/report.pdf
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if azbutils.IsRemote(args[0]) || !azbutils.IsRemote(args[1]) {
+			return usageErrorf("verify takes a local path, then a remote path")
+		}
+		client, err := newLibClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newContext(config.OpTransfer)
+		defer cancel()
+
+		res, err := client.Verify(ctx, args[0], args[1], &azbutils.VerifyOptions{
+			Download: verifyDownload,
+			OnEntry: func(e azbutils.VerifyEntry) {
+				if e.Status == azbutils.VerifyMatch && verifyQuiet {
+					return
+				}
+				fmt.Printf("%-14s %s\n", e.Status, e.Path)
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d file(s) compared, %d difference(s).\n", len(res.Entries), res.Differences)
+		if res.Differences > 0 {
+			return fmt.Errorf("'%s' and '%s' differ", args[0], args[1])
+		}
+		return nil
+	},
+}
+
+// addCheckMD5Flag adds --check-md5 to a command that downloads
+func addCheckMD5Flag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&checkMD5, "check-md5", "fail", "On a download whose data does not match the blob's stored MD5: fail, warn or off")
+}
+
+// checksumMode returns the --check-md5 mode
+func checksumMode() (azbutils.ChecksumMode, error) {
+	mode, err := azbutils.ParseChecksumMode(checkMD5)
+	if err != nil {
+		return 0, &usageError{err: err}
+	}
+	return mode, nil
+}
+
+// warnChecksumMismatch reports a mismatch in --check-md5 warn mode
+func warnChecksumMismatch(e *azbutils.ChecksumError) {
+	fmt.Fprintln(os.Stderr, "Warning:", e)
+}
+
+func init() {
+	verifyCmd.Flags().BoolVarP(&verifyQuiet, "quiet", "q", false, "Only list differences")
+	verifyCmd.Flags().BoolVar(&verifyDownload, "download", false, "Hash the blob contents even when a stored MD5 exists")
+}
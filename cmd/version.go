@@ -3,6 +3,9 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -13,3 +16,48 @@ var versionCmd = &cobra.Command{
 		fmt.Println("azbutils version:", version)
 	},
 }
+
+var versionPromoteCmd = &cobra.Command{
+	Use:   "promote <az://account//container/blob> <versionId>",
+	Short: "Restore a previous blob version by copying it over the current blob",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := parsePath(args[0])
+		if err != nil {
+			return err
+		}
+		if p.SubPath == "" {
+			return usageErrorf("path must point to a blob, not a container")
+		}
+		versionID := args[1]
+		if !azpath.IsVersionID(versionID) {
+			return usageErrorf("invalid version ID '%s': expected a timestamp such as 2024-01-01T00:00:00.0000000Z", versionID)
+		}
+
+		st, err := storeForPath(p)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newContext(config.OpTransfer)
+		defer cancel()
+
+		// Copy waits for the service to finish the copy, which can be
+		// asynchronous for large blobs
+		err = st.Copy(ctx, p.Container, p.SubPath, p.Container, p.SubPath, &store.CopyOptions{SourceVersionID: versionID})
+		if err != nil {
+			return fmt.Errorf("failed to promote version: %w", err)
+		}
+
+		fmt.Printf("Promoted version %s of %s", versionID, p.BuildFull(p.SubPath))
+		if obj, err := st.Properties(ctx, p.Container, p.SubPath, nil); err == nil && obj.VersionID != "" {
+			fmt.Printf(" (new version: %s)", obj.VersionID)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+func init() {
+	versionCmd.AddCommand(versionPromoteCmd)
+}
@@ -0,0 +1,48 @@
+// Package atomicfile writes files through a temporary file that is renamed
+// into place, so readers never see a partial file.
+package atomicfile
+
+import (
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WriteFile calls write with a temporary file next to path and renames it
+// over path once write succeeded. If write fails, path is left as it was.
+// An existing file keeps its permissions; a new one gets perm less the umask.
+func WriteFile(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	info, statErr := os.Stat(path)
+	tmp, err := createTemp(path, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if err == nil && statErr == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// createTemp is os.CreateTemp with permissions perm, which the umask applies to
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	dir, base := filepath.Split(path)
+	for range 100 {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".tmp")
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: filepath.Join(dir, "."+base+".*.tmp"), Err: os.ErrExist}
+}
@@ -0,0 +1,81 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := WriteFile(path, 0644, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want the write error", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("after a failed write the file holds %q, want \"old\"", data)
+	}
+
+	err = WriteFile(path, 0644, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("file holds %q, want \"new\"", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files left in the directory, want 1", len(entries))
+	}
+}
+
+func TestWriteFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits")
+	}
+	dir := t.TempDir()
+	write := func(w io.Writer) error { return nil }
+
+	// A new file gets perm less the umask, as with os.OpenFile
+	ref := filepath.Join(dir, "ref")
+	f, err := os.OpenFile(ref, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	refInfo, _ := os.Stat(ref)
+	path := filepath.Join(dir, "new")
+	if err := WriteFile(path, 0666, write); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode() != refInfo.Mode() {
+		t.Errorf("new file mode = %v, want %v", info.Mode(), refInfo.Mode())
+	}
+
+	// An existing file keeps its mode
+	for _, mode := range []os.FileMode{0600, 0664} {
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile(path, 0666, write); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != mode {
+			t.Errorf("existing file mode = %v, want %v", info.Mode().Perm(), mode)
+		}
+	}
+}
@@ -2,67 +2,287 @@ package azpath
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 type BlobPath struct {
-	Account   string
-	Container string
-	SubPath   string
-	Type      string // "az" or "url"
+	Account    string
+	Container  string
+	SubPath    string
+	VersionID  string
+	Type       string // "az" or "url"
+	ServiceURL string // blob service base URL, set for "url" paths
+}
+
+// containerPattern matches container names, including $root and $web
+var containerPattern = regexp.MustCompile(`^(\$root|\$web|[a-z0-9][a-z0-9-]{2,62})$`)
+
+// DefaultEndpointSuffixes are the storage endpoint suffixes of the public,
+// US Government and China clouds
+var DefaultEndpointSuffixes = []string{
+	"core.windows.net",
+	"core.usgovcloudapi.net",
+	"core.chinacloudapi.cn",
+}
+
+// Endpoint maps a blob service URL to the config account that owns it
+type Endpoint struct {
+	Account string
+	URL     string // e.g. https://myaccount.blob.core.windows.net or http://127.0.0.1:10000/devstoreaccount1
+}
+
+// Parser parses paths with knowledge of the configured endpoints
+type Parser struct {
+	// EndpointSuffixes are recognised in host-style URLs in addition to DefaultEndpointSuffixes
+	EndpointSuffixes []string
+	// Endpoints are tried first, so configured accounts win over name-based guesses
+	Endpoints []Endpoint
+	// Aliases map a name to a path prefix, so "@logs/app.log" expands to Aliases["logs"] + "app.log"
+	Aliases map[string]string
+	// Account is an explicitly selected account (--account). Short paths use
+	// it, and full paths naming a different account are rejected.
+	Account string
+	// DefaultAccount is used by short paths when Account is empty
+	DefaultAccount string
+	// AllowBare accepts "<container>/<path>" without a scheme
+	AllowBare bool
+}
+
+// IsRemote reports whether input looks like an az:// path, blob service URL or @alias
+func IsRemote(input string) bool {
+	return strings.HasPrefix(input, "az://") || strings.HasPrefix(input, "https://") ||
+		strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "@")
 }
 
 // Parse takes an Azure Blob URL or az:// path and normalizes it into BlobPath
+// using only the default endpoint suffixes
 func Parse(input string) (*BlobPath, error) {
-	if strings.HasPrefix(input, "https://") {
-		re := regexp.MustCompile(`https://([^.]+)\.blob\.core\.windows\.net/([^/]+)(/(.*))?`)
-		matches := re.FindStringSubmatch(input)
-		if len(matches) >= 3 {
-			subpath := ""
-			if len(matches) > 4 && matches[4] != "" {
-				subpath = matches[4]
-			}
-			return &BlobPath{
-				Account:   matches[1],
-				Container: matches[2],
-				SubPath:   subpath,
-				Type:      "url",
-			}, nil
+	return (&Parser{}).Parse(input)
+}
+
+// Parse takes an Azure Blob URL or az:// path and normalizes it into BlobPath.
+// az://<container>/<path> (no "//") and, with AllowBare, <container>/<path>
+// resolve against Account or DefaultAccount. A blob version can be selected
+// with a "?versionid=" query on URLs or a "#<versionId>" suffix otherwise;
+// any other '#' is part of the blob name.
+func (ps *Parser) Parse(input string) (*BlobPath, error) {
+	if strings.HasPrefix(input, "@") {
+		expanded, err := ps.ExpandAlias(input)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("invalid Azure blob URL: %s", input)
+		input = expanded
+	}
+
+	if strings.HasPrefix(input, "https://") || strings.HasPrefix(input, "http://") {
+		return ps.parseURL(input)
 	}
 
 	if strings.HasPrefix(input, "az://") {
 		path := strings.TrimPrefix(input, "az://")
-		parts := strings.SplitN(path, "//", 2)
-		if len(parts) < 2 {
+		path, versionID := splitVersion(path)
+		// Only a "//" right after the first segment separates an account;
+		// az://<container>/dir//file is a short path with "//" in its name
+		account, rest, found := strings.Cut(path, "/")
+		if !found || !strings.HasPrefix(rest, "/") {
+			// az://<container>/<path> uses the selected or default account
+			return ps.shortPath(path, versionID, input)
+		}
+		if account == "" {
 			return nil, fmt.Errorf("invalid az path format. expected az://<account>//<container>")
 		}
-		containerParts := strings.SplitN(parts[1], "/", 2)
-		subpath := ""
-		if len(containerParts) == 2 {
-			subpath = containerParts[1]
+		if ps.Account != "" && ps.Account != account {
+			return nil, fmt.Errorf("ambiguous account: path '%s' names account '%s' but --account is '%s'", input, account, ps.Account)
 		}
-		return &BlobPath{
-			Account:   parts[0],
-			Container: containerParts[0],
-			SubPath:   subpath,
-			Type:      "az",
-		}, nil
+		return newAzPath(account, rest[1:], versionID), nil
+	}
+
+	if ps.AllowBare && input != "" {
+		path, versionID := splitVersion(input)
+		return ps.shortPath(path, versionID, input)
 	}
 
 	return nil, fmt.Errorf("unsupported path format: %s", input)
 }
 
+// shortPath resolves "<container>/<path>" against the selected or default account
+func (ps *Parser) shortPath(path, versionID, input string) (*BlobPath, error) {
+	account := ps.Account
+	if account == "" {
+		account = ps.DefaultAccount
+	}
+	if account == "" {
+		return nil, fmt.Errorf("path '%s' does not name an account and no default account is set; "+
+			"use az://<account>//<container>/<path>, pass --account, or run 'azbutils account default <name>'", input)
+	}
+	bp := newAzPath(account, path, versionID)
+	if !containerPattern.MatchString(bp.Container) {
+		return nil, fmt.Errorf("invalid path '%s': '%s' is not a valid container name", input, bp.Container)
+	}
+	return bp, nil
+}
+
+func newAzPath(account, path, versionID string) *BlobPath {
+	container, subpath, _ := strings.Cut(path, "/")
+	return &BlobPath{
+		Account:   account,
+		Container: container,
+		SubPath:   subpath,
+		VersionID: versionID,
+		Type:      "az",
+	}
+}
+
+// splitVersion splits a "#<versionId>" suffix off path. '#' is valid in blob
+// names, so only a suffix that parses as a version ID counts.
+func splitVersion(path string) (string, string) {
+	if i := strings.LastIndex(path, "#"); i >= 0 && IsVersionID(path[i+1:]) {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// IsVersionID reports whether s has the form of a blob version ID, a UTC
+// timestamp such as 2024-01-01T00:00:00.0000000Z
+func IsVersionID(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil && strings.HasSuffix(s, "Z")
+}
+
+// ExpandAlias replaces a leading @name with the alias target
+func (ps *Parser) ExpandAlias(input string) (string, error) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(input, "@"), "/")
+	target, ok := ps.Aliases[name]
+	if !ok {
+		return "", fmt.Errorf("unknown alias: @%s", name)
+	}
+	if strings.HasPrefix(target, "@") {
+		return "", fmt.Errorf("alias @%s points to another alias (%s); aliases must expand to a path", name, target)
+	}
+	if rest == "" {
+		return target, nil
+	}
+	if !strings.HasSuffix(target, "/") {
+		target += "/"
+	}
+	return target + rest, nil
+}
+
+func (ps *Parser) parseURL(input string) (*BlobPath, error) {
+	u, err := url.Parse(input)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid Azure blob URL: %s", input)
+	}
+	versionID := u.Query().Get("versionid")
+	u.RawQuery = ""
+	u.Fragment = ""
+	clean := strings.TrimSuffix(u.String(), "/")
+
+	// Configured service URLs, including path-style emulator endpoints
+	for _, ep := range ps.Endpoints {
+		base := strings.TrimSuffix(ep.URL, "/")
+		if base == "" {
+			continue
+		}
+		if strings.EqualFold(clean, base) || strings.HasPrefix(strings.ToLower(clean), strings.ToLower(base)+"/") {
+			return newURLPath(ep.Account, base, clean[len(base):], versionID, input)
+		}
+	}
+
+	// Host-style: <account>.blob.<suffix>
+	host := strings.ToLower(u.Hostname())
+	for _, suffix := range ps.suffixes() {
+		account, ok := strings.CutSuffix(host, ".blob."+suffix)
+		if ok && account != "" && !strings.Contains(account, ".") {
+			base := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+			return newURLPath(account, base, u.Path, versionID, input)
+		}
+	}
+
+	// Path-style: <scheme>://<host>[:port]/<account>/<container>, as used by emulators
+	if u.Port() != "" || host == "localhost" || net.ParseIP(host) != nil {
+		account, rest, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		if account != "" {
+			base := fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, account)
+			return newURLPath(account, base, "/"+rest, versionID, input)
+		}
+	}
+
+	return nil, fmt.Errorf("invalid Azure blob URL: %s", input)
+}
+
+func newURLPath(account, base, path, versionID, input string) (*BlobPath, error) {
+	container, subpath, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if container == "" {
+		return nil, fmt.Errorf("invalid Azure blob URL (missing container): %s", input)
+	}
+	return &BlobPath{
+		Account:    account,
+		Container:  container,
+		SubPath:    subpath,
+		VersionID:  versionID,
+		Type:       "url",
+		ServiceURL: base,
+	}, nil
+}
+
+func (ps *Parser) suffixes() []string {
+	return append(append([]string{}, DefaultEndpointSuffixes...), ps.EndpointSuffixes...)
+}
+
+// Child returns a path to another blob in the same container
+func (p *BlobPath) Child(subPath string) *BlobPath {
+	return &BlobPath{
+		Account:    p.Account,
+		Container:  p.Container,
+		SubPath:    subPath,
+		Type:       p.Type,
+		ServiceURL: p.ServiceURL,
+	}
+}
+
+// DirPrefix returns the listing prefix for everything under the path as a
+// virtual directory: "logs" becomes "logs/", so that "logs-archive/..." is not
+// included. The container root and paths ending in "/" are kept as they are.
+func (p *BlobPath) DirPrefix() string {
+	if p.SubPath != "" && !strings.HasSuffix(p.SubPath, "/") {
+		return p.SubPath + "/"
+	}
+	return p.SubPath
+}
+
 // BuildFull formats a blob name back into a full path depending on input type
 func (p *BlobPath) BuildFull(blobName string) string {
 	switch p.Type {
 	case "url":
-		return fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", p.Account, p.Container, blobName)
+		base := p.ServiceURL
+		if base == "" {
+			base = fmt.Sprintf("https://%s.blob.core.windows.net", p.Account)
+		}
+		return fmt.Sprintf("%s/%s/%s", base, p.Container, blobName)
 	case "az":
 		return fmt.Sprintf("az://%s//%s/%s", p.Account, p.Container, blobName)
 	default:
 		return blobName
 	}
 }
+
+// BuildFullVersion is like BuildFull but pins the result to a blob version
+func (p *BlobPath) BuildFullVersion(blobName, versionID string) string {
+	full := p.BuildFull(blobName)
+	if versionID == "" {
+		return full
+	}
+	switch p.Type {
+	case "url":
+		return full + "?versionid=" + url.QueryEscape(versionID)
+	case "az":
+		return full + "#" + versionID
+	default:
+		return full
+	}
+}
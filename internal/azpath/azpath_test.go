@@ -0,0 +1,66 @@
+package azpath
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	ps := &Parser{DefaultAccount: "def"}
+	tests := []struct {
+		input                                string
+		account, container, subPath, version string
+	}{
+		{"az://acct//data/dir/file.txt", "acct", "data", "dir/file.txt", ""},
+		{"az://acct//data", "acct", "data", "", ""},
+		{"az://data/dir/file.txt", "def", "data", "dir/file.txt", ""},
+		// "//" after the first segment only separates an account
+		{"az://data/dir//file.txt", "def", "data", "dir//file.txt", ""},
+		{"az://acct//data/dir//file.txt", "acct", "data", "dir//file.txt", ""},
+		// Only a version timestamp after '#' selects a version
+		{"az://acct//data/f.txt#2024-01-01T00:00:00.0000000Z", "acct", "data", "f.txt", "2024-01-01T00:00:00.0000000Z"},
+		{"az://acct//data/notes#1.txt", "acct", "data", "notes#1.txt", ""},
+		{"az://data/a#b#2024-01-01T00:00:00Z", "def", "data", "a#b", "2024-01-01T00:00:00Z"},
+		{"https://acct.blob.core.windows.net/data/f.txt?versionid=2024-01-01T00:00:00.0000000Z", "acct", "data", "f.txt", "2024-01-01T00:00:00.0000000Z"},
+	}
+	for _, tt := range tests {
+		p, err := ps.Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if p.Account != tt.account || p.Container != tt.container || p.SubPath != tt.subPath || p.VersionID != tt.version {
+			t.Errorf("Parse(%q) = %s, %s, %s, %s; want %s, %s, %s, %s", tt.input,
+				p.Account, p.Container, p.SubPath, p.VersionID, tt.account, tt.container, tt.subPath, tt.version)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	ps := &Parser{DefaultAccount: "def"}
+	for _, input := range []string{
+		"az:////data",
+		"az://Not_A_Container/file",
+		"data/file",
+	} {
+		if p, err := ps.Parse(input); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", input, p)
+		}
+	}
+
+	ps.Account = "acct"
+	if _, err := ps.Parse("az://other//data/f.txt"); err == nil {
+		t.Error("Parse accepted an account other than --account")
+	}
+}
+
+func TestDirPrefix(t *testing.T) {
+	for subPath, want := range map[string]string{
+		"":      "",
+		"logs":  "logs/",
+		"logs/": "logs/",
+		"a/b":   "a/b/",
+	} {
+		p := &BlobPath{SubPath: subPath}
+		if got := p.DirPrefix(); got != want {
+			t.Errorf("DirPrefix of %q = %q, want %q", subPath, got, want)
+		}
+	}
+}
@@ -6,49 +6,142 @@ import (
 	"os"
 	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/secret"
 )
 
+// secretSource returns an account secret given its environment variable suffix
+type secretSource func(suffix string) (string, error)
+
+// NewClientFromConfigAccount creates a new, uncached client for acct. Commands
+// should use ClientFor to share clients and credentials.
 func NewClientFromConfigAccount(acct *config.AccountConfig) (*azblob.Client, error) {
-	envName := strings.ToUpper(acct.AccountName)
+	return newClient(acct, storedSecret(acct), currentRegistry().clientOptions())
+}
+
+// NewClientWithSecret creates a client using a secret that has not been
+// stored yet, e.g. to test an account before saving it. An empty value falls
+// back to the account's stored secret.
+func NewClientWithSecret(acct *config.AccountConfig, value string) (*azblob.Client, error) {
+	if value == "" {
+		return NewClientFromConfigAccount(acct)
+	}
+	return newClient(acct, func(string) (string, error) { return value, nil }, currentRegistry().clientOptions())
+}
+
+func newClient(acct *config.AccountConfig, getSecret secretSource, opts *azblob.ClientOptions) (*azblob.Client, error) {
 	switch acct.AuthMethod {
 	case "connection-string":
-		cs := os.Getenv(fmt.Sprintf("%s_CONNECTION_STRING", envName))
-		if cs == "" {
-			return nil, fmt.Errorf("Missing environment variable: %s_CONNECTION_STRING", envName)
-		}
-		return azblob.NewClientFromConnectionString(cs, nil)
-	case "shared-key":
-		key := os.Getenv(fmt.Sprintf("%s_ACCOUNT_KEY", envName))
-		if key == "" {
-			return nil, fmt.Errorf("Missing environment variable: %s_ACCOUNT_KEY", envName)
+		cs, err := getSecret("CONNECTION_STRING")
+		if err != nil {
+			return nil, err
 		}
-		cred, err := azblob.NewSharedKeyCredential(acct.AccountName, key)
+		return azblob.NewClientFromConnectionString(cs, opts)
+	case "shared-key", "emulator":
+		cred, err := newSharedKeyCredential(acct, getSecret)
 		if err != nil {
 			return nil, err
 		}
-		return azblob.NewClientWithSharedKeyCredential(acct.ServiceURL, cred, nil)
+		return azblob.NewClientWithSharedKeyCredential(acct.ServiceURL, cred, opts)
 	case "sas":
-		sas := os.Getenv(fmt.Sprintf("%s_SAS_URL", envName))
-		if sas == "" {
-			return nil, fmt.Errorf("Missing environment variable: %s_SAS_URL", envName)
+		sas, err := getSecret("SAS_URL")
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClientWithNoCredential(sas, opts)
+	default:
+		if !IsTokenAuth(acct.AuthMethod) {
+			return nil, fmt.Errorf("Unsupported auth method: %s", acct.AuthMethod)
+		}
+		cred, err := newTokenCredential(acct, getSecret, opts.ClientOptions)
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClient(acct.ServiceURL, cred, opts)
+	}
+}
+
+// NewSharedKeyCredential builds a shared key credential for shared-key,
+// connection-string and emulator accounts
+func NewSharedKeyCredential(acct *config.AccountConfig) (*azblob.SharedKeyCredential, error) {
+	return newSharedKeyCredential(acct, storedSecret(acct))
+}
+
+func newSharedKeyCredential(acct *config.AccountConfig, getSecret secretSource) (*azblob.SharedKeyCredential, error) {
+	switch acct.AuthMethod {
+	case "emulator":
+		return azblob.NewSharedKeyCredential(EmulatorAccountName, EmulatorAccountKey)
+	case "shared-key":
+		key, err := getSecret("ACCOUNT_KEY")
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewSharedKeyCredential(acct.AccountName, key)
+	case "connection-string":
+		cs, err := getSecret("CONNECTION_STRING")
+		if err != nil {
+			return nil, err
 		}
-		return azblob.NewClientWithNoCredential(sas, nil)
-	case "az-login", "default":
-		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		parts, err := ParseConnectionString(cs)
 		if err != nil {
 			return nil, err
 		}
-		return azblob.NewClient(acct.ServiceURL, cred, nil)
+		if parts["AccountName"] == "" || parts["AccountKey"] == "" {
+			return nil, fmt.Errorf("connection string has no AccountName/AccountKey")
+		}
+		return azblob.NewSharedKeyCredential(parts["AccountName"], parts["AccountKey"])
+	default:
+		return nil, fmt.Errorf("auth method %s has no account key", acct.AuthMethod)
+	}
+}
+
+func storedSecret(acct *config.AccountConfig) secretSource {
+	return func(suffix string) (string, error) {
+		return accountSecret(acct, suffix)
+	}
+}
+
+// accountSecret resolves the account's secret reference, falling back to the
+// <ACCOUNT>_<suffix> environment variable
+func accountSecret(acct *config.AccountConfig, suffix string) (string, error) {
+	if acct.SecretRef != "" {
+		return secret.Resolve(acct.SecretRef)
+	}
+	name := SecretEnvName(acct, suffix)
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("Missing environment variable: %s", name)
+	}
+	return value, nil
+}
+
+// SecretEnvName is the environment variable holding an account's secret
+func SecretEnvName(acct *config.AccountConfig, suffix string) string {
+	return fmt.Sprintf("%s_%s", strings.ToUpper(acct.AccountName), suffix)
+}
+
+// SecretEnvSuffix returns the environment variable suffix for the secret an
+// auth method needs, or "" if it needs none
+func SecretEnvSuffix(authMethod string) string {
+	switch authMethod {
+	case "connection-string":
+		return "CONNECTION_STRING"
+	case "shared-key":
+		return "ACCOUNT_KEY"
+	case "sas":
+		return "SAS_URL"
+	case "service-principal-secret":
+		return "CLIENT_SECRET"
+	case "service-principal-cert":
+		return "CERTIFICATE_PASSWORD"
 	default:
-		return nil, fmt.Errorf("Unsupported auth method: %s", acct.AuthMethod)
+		return ""
 	}
 }
 
-func TestConnection(client *azblob.Client) error {
-	ctx := context.Background()
+// TestConnection lists the account's containers, giving up when ctx is done
+func TestConnection(ctx context.Context, client *azblob.Client) error {
 	pager := client.NewListContainersPager(nil)
 
 	if pager.More() {
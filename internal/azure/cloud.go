@@ -0,0 +1,66 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+type cloudInfo struct {
+	endpointSuffix string
+	configuration  cloud.Configuration
+}
+
+var clouds = map[string]cloudInfo{
+	"public":       {"core.windows.net", cloud.AzurePublic},
+	"usgovernment": {"core.usgovcloudapi.net", cloud.AzureGovernment},
+	"china":        {"core.chinacloudapi.cn", cloud.AzureChina},
+}
+
+// CloudNames lists the supported cloud names
+var CloudNames = []string{"public", "usgovernment", "china"}
+
+func lookupCloud(name string) (cloudInfo, error) {
+	if name == "" {
+		name = "public"
+	}
+	info, ok := clouds[strings.ToLower(name)]
+	if !ok {
+		return cloudInfo{}, fmt.Errorf("unknown cloud: %s (expected one of %s)", name, strings.Join(CloudNames, ", "))
+	}
+	return info, nil
+}
+
+// ValidateCloud checks that a cloud name is supported
+func ValidateCloud(name string) error {
+	_, err := lookupCloud(name)
+	return err
+}
+
+// EndpointSuffix returns the storage endpoint suffix for an account, e.g. core.windows.net
+func EndpointSuffix(acct *config.AccountConfig) string {
+	if acct.EndpointSuffix != "" {
+		return acct.EndpointSuffix
+	}
+	info, err := lookupCloud(acct.Cloud)
+	if err != nil {
+		return clouds["public"].endpointSuffix
+	}
+	return info.endpointSuffix
+}
+
+// DefaultServiceURL derives the blob service URL for a storage account in the account's cloud
+func DefaultServiceURL(name string, acct *config.AccountConfig) string {
+	return fmt.Sprintf("https://%s.blob.%s", name, EndpointSuffix(acct))
+}
+
+// cloudConfiguration returns the azcore cloud configuration passed to credentials
+func cloudConfiguration(acct *config.AccountConfig) (cloud.Configuration, error) {
+	info, err := lookupCloud(acct.Cloud)
+	if err != nil {
+		return cloud.Configuration{}, err
+	}
+	return info.configuration, nil
+}
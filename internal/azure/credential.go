@@ -0,0 +1,129 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/secret"
+)
+
+// AuthMethods lists every supported auth method, in prompt order
+var AuthMethods = []string{
+	"az-login",
+	"connection-string",
+	"shared-key",
+	"sas",
+	"azure-cli",
+	"service-principal-secret",
+	"service-principal-cert",
+	"managed-identity",
+	"workload-identity",
+	"device-code",
+	"interactive-browser",
+}
+
+// IsTokenAuth reports whether an auth method authenticates with Microsoft Entra ID
+func IsTokenAuth(authMethod string) bool {
+	switch authMethod {
+	case "az-login", "default", "azure-cli", "service-principal-secret", "service-principal-cert",
+		"managed-identity", "workload-identity", "device-code", "interactive-browser":
+		return true
+	default:
+		return false
+	}
+}
+
+// newTokenCredential builds the azidentity credential matching the account's auth method
+func newTokenCredential(acct *config.AccountConfig, getSecret secretSource, clientOpts policy.ClientOptions) (azcore.TokenCredential, error) {
+	cloudCfg, err := cloudConfiguration(acct)
+	if err != nil {
+		return nil, err
+	}
+	clientOpts.Cloud = cloudCfg
+
+	switch acct.AuthMethod {
+	case "az-login", "default":
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      acct.TenantID,
+		})
+	case "azure-cli":
+		return azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{
+			TenantID: acct.TenantID,
+		})
+	case "service-principal-secret":
+		if err := requireIdentity(acct); err != nil {
+			return nil, err
+		}
+		clientSecret, err := getSecret("CLIENT_SECRET")
+		if err != nil {
+			return nil, err
+		}
+		return azidentity.NewClientSecretCredential(acct.TenantID, acct.ClientID, clientSecret, &azidentity.ClientSecretCredentialOptions{
+			ClientOptions: clientOpts,
+		})
+	case "service-principal-cert":
+		if err := requireIdentity(acct); err != nil {
+			return nil, err
+		}
+		if acct.CertificatePath == "" {
+			return nil, fmt.Errorf("%s requires a certificate path", acct.AuthMethod)
+		}
+		data, err := os.ReadFile(acct.CertificatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate: %w", err)
+		}
+		// The certificate password is optional
+		password, err := getSecret("CERTIFICATE_PASSWORD")
+		if err != nil && acct.SecretRef != "" && !errors.Is(err, secret.ErrNotFound) {
+			return nil, err
+		}
+		certs, key, err := azidentity.ParseCertificates(data, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		return azidentity.NewClientCertificateCredential(acct.TenantID, acct.ClientID, certs, key, &azidentity.ClientCertificateCredentialOptions{
+			ClientOptions: clientOpts,
+		})
+	case "managed-identity":
+		opts := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOpts}
+		if acct.ClientID != "" {
+			opts.ID = azidentity.ClientID(acct.ClientID)
+		}
+		return azidentity.NewManagedIdentityCredential(opts)
+	case "workload-identity":
+		// Empty fields fall back to AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_FEDERATED_TOKEN_FILE
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      acct.TenantID,
+			ClientID:      acct.ClientID,
+			TokenFilePath: acct.TokenFilePath,
+		})
+	case "device-code":
+		return azidentity.NewDeviceCodeCredential(&azidentity.DeviceCodeCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      acct.TenantID,
+			ClientID:      acct.ClientID,
+		})
+	case "interactive-browser":
+		return azidentity.NewInteractiveBrowserCredential(&azidentity.InteractiveBrowserCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      acct.TenantID,
+			ClientID:      acct.ClientID,
+		})
+	default:
+		return nil, fmt.Errorf("Unsupported auth method: %s", acct.AuthMethod)
+	}
+}
+
+func requireIdentity(acct *config.AccountConfig) error {
+	if acct.TenantID == "" || acct.ClientID == "" {
+		return fmt.Errorf("%s requires a tenant ID and client ID", acct.AuthMethod)
+	}
+	return nil
+}
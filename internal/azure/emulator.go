@@ -0,0 +1,19 @@
+package azure
+
+import "fmt"
+
+// Well-known Azurite development account. The key is public and documented
+// by Microsoft, so emulator accounts need no secret.
+const (
+	EmulatorAccountName = "devstoreaccount1"
+	EmulatorAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	EmulatorBlobURL     = "http://127.0.0.1:10000"
+)
+
+// EmulatorServiceURL returns the path-style service URL for the emulator account at host
+func EmulatorServiceURL(host string) string {
+	if host == "" {
+		host = EmulatorBlobURL
+	}
+	return fmt.Sprintf("%s/%s", host, EmulatorAccountName)
+}
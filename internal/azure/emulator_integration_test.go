@@ -0,0 +1,133 @@
+//go:build integration
+
+// Integration tests against the storage emulator (Azurite). Start one, e.g.
+// "azurite-blob --loose", and run "go test -tags integration ./...";
+// AZBUTILS_EMULATOR_URL overrides the default http://127.0.0.1:10000.
+package azure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+func emulatorAccount() *config.AccountConfig {
+	return &config.AccountConfig{
+		AuthMethod:  "emulator",
+		ServiceURL:  EmulatorServiceURL(os.Getenv("AZBUTILS_EMULATOR_URL")),
+		AccountName: EmulatorAccountName,
+	}
+}
+
+// newEmulatorContainer creates a container that is deleted after the test
+func newEmulatorContainer(t *testing.T) (*azblob.Client, *container.Client, string) {
+	t.Helper()
+	client, err := ClientFor("emulator", emulatorAccount())
+	if err != nil {
+		t.Fatalf("ClientFor: %v", err)
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	name := "it-" + hex.EncodeToString(b)
+
+	ctx := context.Background()
+	if _, err := client.CreateContainer(ctx, name, nil); err != nil {
+		t.Fatalf("create container (is the emulator running?): %v", err)
+	}
+	t.Cleanup(func() { client.DeleteContainer(context.Background(), name, nil) })
+	return client, client.ServiceClient().NewContainerClient(name), name
+}
+
+func TestEmulatorShareLink(t *testing.T) {
+	ctx := context.Background()
+	client, containerClient, name := newEmulatorContainer(t)
+	if _, err := client.UploadBuffer(ctx, name, "f.txt", []byte("hello"), nil); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	policy := SharePolicyPrefix + "test"
+	now := time.Now()
+	if err := AddAccessPolicy(ctx, containerClient, policy, "r", now.Add(-5*time.Minute), now.Add(time.Hour)); err != nil {
+		t.Fatalf("AddAccessPolicy: %v", err)
+	}
+	url, err := GenerateSASURL(ctx, emulatorAccount(), client, SASOptions{
+		Container: name,
+		BlobName:  "f.txt",
+		Protocol:  SASProtocol(client.URL()),
+		Policy:    policy,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSASURL: %v", err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("GET %s = %d %q, want 200 \"hello\"", url, resp.StatusCode, body)
+	}
+
+	if err := RemoveAccessPolicy(ctx, containerClient, policy); err != nil {
+		t.Fatalf("RemoveAccessPolicy: %v", err)
+	}
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Errorf("revoked link still works")
+	}
+}
+
+func TestEmulatorAddAccessPolicyPrunesExpiredShares(t *testing.T) {
+	ctx := context.Background()
+	_, containerClient, _ := newEmulatorContainer(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	policy := func(id string, expiry time.Time) *container.SignedIdentifier {
+		return &container.SignedIdentifier{ID: to.Ptr(id), AccessPolicy: &container.AccessPolicy{
+			Permission: to.Ptr("r"), Start: to.Ptr(now.Add(-2 * time.Hour)), Expiry: to.Ptr(expiry),
+		}}
+	}
+	acl := []*container.SignedIdentifier{policy(SharePolicyPrefix+"old", now.Add(-time.Hour))}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		acl = append(acl, policy(id, now.Add(time.Hour)))
+	}
+	if _, err := containerClient.SetAccessPolicy(ctx, &container.SetAccessPolicyOptions{ContainerACL: acl}); err != nil {
+		t.Fatalf("SetAccessPolicy: %v", err)
+	}
+
+	if err := AddAccessPolicy(ctx, containerClient, SharePolicyPrefix+"new", "r", now, now.Add(time.Hour)); err != nil {
+		t.Fatalf("AddAccessPolicy with an expired share policy: %v", err)
+	}
+	resp, err := containerClient.GetAccessPolicy(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAccessPolicy: %v", err)
+	}
+	var ids []string
+	for _, si := range resp.SignedIdentifiers {
+		ids = append(ids, *si.ID)
+	}
+	if got := strings.Join(ids, ","); got != "a,b,c,d,"+SharePolicyPrefix+"new" {
+		t.Errorf("policies = %s, want the expired share policy replaced", got)
+	}
+
+	if err := AddAccessPolicy(ctx, containerClient, SharePolicyPrefix+"more", "r", now, now.Add(time.Hour)); err == nil {
+		t.Error("AddAccessPolicy succeeded on a full container")
+	}
+}
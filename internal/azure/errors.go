@@ -0,0 +1,74 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// ErrorKind is the category of a failed storage or authentication call
+type ErrorKind int
+
+const (
+	ErrorOther ErrorKind = iota
+	ErrorNotFound
+	ErrorAuth
+	ErrorThrottled
+	ErrorConflict
+	ErrorPrecondition
+)
+
+// ClassifyError returns the category of err, looking at the storage error
+// code first and the HTTP status second
+func ClassifyError(err error) ErrorKind {
+	var authFailed *azidentity.AuthenticationFailedError
+	var authRequired *azidentity.AuthenticationRequiredError
+	if errors.As(err, &authFailed) || errors.As(err, &authRequired) {
+		return ErrorAuth
+	}
+
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return ErrorOther
+	}
+
+	switch bloberror.Code(respErr.ErrorCode) {
+	case bloberror.BlobNotFound, bloberror.ContainerNotFound, bloberror.ResourceNotFound:
+		return ErrorNotFound
+	case bloberror.AuthenticationFailed, bloberror.AuthorizationFailure,
+		bloberror.AuthorizationPermissionMismatch, bloberror.AuthorizationResourceTypeMismatch,
+		bloberror.AuthorizationSourceIPMismatch, bloberror.AuthorizationProtocolMismatch,
+		bloberror.AuthorizationServiceMismatch, bloberror.InsufficientAccountPermissions,
+		bloberror.NoAuthenticationInformation:
+		return ErrorAuth
+	case bloberror.ServerBusy, bloberror.OperationTimedOut:
+		return ErrorThrottled
+	}
+
+	switch respErr.StatusCode {
+	case http.StatusNotFound:
+		return ErrorNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorAuth
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return ErrorThrottled
+	case http.StatusConflict:
+		return ErrorConflict
+	case http.StatusPreconditionFailed:
+		return ErrorPrecondition
+	}
+	return ErrorOther
+}
+
+// ErrorCode returns the storage error code of err (e.g. "LeaseIdMissing"),
+// or "" if err is not a service error
+func ErrorCode(err error) string {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.ErrorCode
+	}
+	return ""
+}
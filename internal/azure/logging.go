@@ -0,0 +1,142 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// logger receives the HTTP exchanges of every client; it discards
+// everything until SetLogger is called
+var logger = slog.New(slog.DiscardHandler)
+
+// SetLogger routes client logging to l. At debug level the SDK's retry and
+// authentication events are logged too.
+func SetLogger(l *slog.Logger) {
+	logger = l
+	if l.Enabled(context.Background(), slog.LevelDebug) {
+		azlog.SetEvents(azlog.EventRetryPolicy, azidentity.EventAuthentication)
+		azlog.SetListener(func(event azlog.Event, msg string) {
+			logger.Debug(msg, "event", event)
+		})
+	}
+}
+
+// redacted replaces secrets in logs and traces
+const redacted = "REDACTED"
+
+// maxErrorBody is how much of an error response body is logged and traced
+const maxErrorBody = 8 << 10
+
+// secretHeaders are never logged; lowercase
+var secretHeaders = map[string]bool{
+	"authorization":                  true,
+	"proxy-authorization":            true,
+	"x-ms-copy-source-authorization": true,
+	"x-ms-encryption-key":            true,
+	"cookie":                         true,
+	"set-cookie":                     true,
+	// Managed identity endpoints of App Service and Service Fabric
+	"x-identity-header": true,
+	"secret":            true,
+}
+
+// RedactURL removes the signature of a SAS URL, keeping the other SAS
+// parameters (permissions, expiry, version) that explain most 403s
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.User = nil
+	query := u.Query()
+	if query.Has("sig") {
+		query.Set("sig", redacted)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// redactHeader returns a header value safe to log
+func redactHeader(name, value string) string {
+	name = strings.ToLower(name)
+	switch {
+	case secretHeaders[name]:
+		return redacted
+	case name == "x-ms-copy-source":
+		return RedactURL(value)
+	default:
+		return value
+	}
+}
+
+func headerAttrs(h http.Header) []any {
+	attrs := make([]any, 0, len(h))
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		attrs = append(attrs, slog.String(name, redactHeader(name, strings.Join(h[name], ", "))))
+	}
+	return attrs
+}
+
+// logPolicy logs and traces every HTTP attempt, including retries
+type logPolicy struct{}
+
+func (logPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	ctx := raw.Context()
+	u := RedactURL(raw.URL.String())
+	logger.DebugContext(ctx, "HTTP request", "method", raw.Method, "url", u, slog.Group("headers", headerAttrs(raw.Header)...))
+
+	start := time.Now()
+	resp, err := req.Next()
+	elapsed := time.Since(start)
+
+	if err != nil {
+		logger.InfoContext(ctx, "HTTP request failed", "method", raw.Method, "url", u, "duration", elapsed.Round(time.Millisecond), "error", err)
+		recordExchange(raw, nil, nil, start, elapsed, err)
+		return resp, err
+	}
+
+	var body []byte
+	if resp.StatusCode >= 400 {
+		body = peekBody(resp)
+	}
+	attrs := []any{"method", raw.Method, "url", u, "status", resp.StatusCode, "duration", elapsed.Round(time.Millisecond)}
+	if id := resp.Header.Get("x-ms-request-id"); id != "" {
+		attrs = append(attrs, "request_id", id)
+	}
+	if code := resp.Header.Get("x-ms-error-code"); code != "" {
+		attrs = append(attrs, "error_code", code)
+	}
+	logger.InfoContext(ctx, "HTTP response", attrs...)
+	logger.DebugContext(ctx, "HTTP response headers", "status", resp.StatusCode, slog.Group("headers", headerAttrs(resp.Header)...))
+	if len(body) > 0 {
+		logger.DebugContext(ctx, "HTTP error body", "status", resp.StatusCode, "body", string(body))
+	}
+	recordExchange(raw, resp, body, start, elapsed, nil)
+	return resp, nil
+}
+
+// peekBody reads the start of resp's body without consuming it
+func peekBody(resp *http.Response) []byte {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	return data
+}
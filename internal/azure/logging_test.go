@@ -0,0 +1,18 @@
+package azure
+
+import "testing"
+
+func TestRedactHeader(t *testing.T) {
+	tests := []struct{ name, value, want string }{
+		{"Authorization", "Bearer token", redacted},
+		{"X-Identity-Header", "token", redacted},
+		{"secret", "token", redacted},
+		{"x-ms-copy-source", "https://acct.blob.core.windows.net/c/f?sig=abc", "https://acct.blob.core.windows.net/c/f?sig=" + redacted},
+		{"x-ms-version", "2025-01-05", "2025-01-05"},
+	}
+	for _, tt := range tests {
+		if got := redactHeader(tt.name, tt.value); got != tt.want {
+			t.Errorf("redactHeader(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package azure
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+// newTransport builds the HTTP client shared by a registry's clients
+func newTransport(n *config.NetworkConfig) (*http.Client, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if n.CABundle != "" {
+		pool, err := loadCABundle(n.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	switch n.Proxy {
+	case "":
+		// keep http.ProxyFromEnvironment
+	case "direct":
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(n.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy '%s': %w", n.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if n.HTTP2.Disable {
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	} else {
+		readIdle, _ := config.ParseDuration("http2.read_idle_timeout", n.HTTP2.ReadIdleTimeout)
+		ping, _ := config.ParseDuration("http2.ping_timeout", n.HTTP2.PingTimeout)
+		transport.HTTP2 = &http.HTTP2Config{
+			SendPingTimeout:  readIdle,
+			PingTimeout:      ping,
+			MaxReadFrameSize: n.HTTP2.MaxReadFrameSize,
+		}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// loadCABundle returns the system roots plus the certificates in path
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// retryOptions converts the retry settings; zero values keep the SDK defaults
+func retryOptions(n *config.NetworkConfig) (policy.RetryOptions, error) {
+	retry := policy.RetryOptions{
+		MaxRetries:  n.MaxRetries,
+		StatusCodes: n.RetryStatusCodes,
+	}
+	var err error
+	if retry.RetryDelay, err = config.ParseDuration("retry_delay", n.RetryDelay); err != nil {
+		return retry, err
+	}
+	if retry.MaxRetryDelay, err = config.ParseDuration("max_retry_delay", n.MaxRetryDelay); err != nil {
+		return retry, err
+	}
+	if retry.TryTimeout, err = config.ParseDuration("try_timeout", n.TryTimeout); err != nil {
+		return retry, err
+	}
+	return retry, nil
+}
@@ -0,0 +1,27 @@
+package azure
+
+import (
+	"net/url"
+
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+// NewPathParser builds a path parser that knows the accounts and aliases in
+// cfg. account, when set, is used for short paths (the --account flag).
+func NewPathParser(cfg *config.Config, account string) *azpath.Parser {
+	parser := &azpath.Parser{
+		Aliases:        cfg.Aliases,
+		Account:        account,
+		DefaultAccount: cfg.DefaultAccount,
+		AllowBare:      true,
+	}
+	for name, acct := range cfg.Accounts {
+		parser.EndpointSuffixes = append(parser.EndpointSuffixes, EndpointSuffix(acct))
+		if u, err := url.Parse(acct.ServiceURL); err == nil && u.Host != "" {
+			u.RawQuery = ""
+			parser.Endpoints = append(parser.Endpoints, azpath.Endpoint{Account: name, URL: u.String()})
+		}
+	}
+	return parser
+}
@@ -0,0 +1,89 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// maxAccessPolicies is the service limit of stored access policies per container
+const maxAccessPolicies = 5
+
+// SharePolicyPrefix starts the IDs of the stored access policies created by
+// share
+const SharePolicyPrefix = "azbutils-share-"
+
+// AddAccessPolicy adds a stored access policy to a container, keeping the
+// existing policies and public access level. Expired share policies are
+// dropped to make room: they no longer grant access.
+func AddAccessPolicy(ctx context.Context, containerClient *container.Client, id, perms string, start, expiry time.Time) error {
+	resp, err := containerClient.GetAccessPolicy(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read access policies: %w", err)
+	}
+
+	now := time.Now()
+	var acl []*container.SignedIdentifier
+	for _, si := range resp.SignedIdentifiers {
+		if si.ID != nil && strings.HasPrefix(*si.ID, SharePolicyPrefix) &&
+			si.AccessPolicy != nil && si.AccessPolicy.Expiry != nil && now.After(*si.AccessPolicy.Expiry) {
+			continue
+		}
+		acl = append(acl, si)
+	}
+	if len(acl) >= maxAccessPolicies {
+		return fmt.Errorf("container already has %d stored access policies (the maximum); revoke one first", maxAccessPolicies)
+	}
+
+	acl = append(acl, &container.SignedIdentifier{
+		ID: to.Ptr(id),
+		AccessPolicy: &container.AccessPolicy{
+			Permission: to.Ptr(perms),
+			Start:      to.Ptr(start.UTC()),
+			Expiry:     to.Ptr(expiry.UTC()),
+		},
+	})
+	_, err = containerClient.SetAccessPolicy(ctx, &container.SetAccessPolicyOptions{
+		Access:       resp.BlobPublicAccess,
+		ContainerACL: acl,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set access policies: %w", err)
+	}
+	return nil
+}
+
+// RemoveAccessPolicy deletes a stored access policy from a container,
+// invalidating every SAS signed against it
+func RemoveAccessPolicy(ctx context.Context, containerClient *container.Client, id string) error {
+	resp, err := containerClient.GetAccessPolicy(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read access policies: %w", err)
+	}
+
+	var acl []*container.SignedIdentifier
+	found := false
+	for _, si := range resp.SignedIdentifiers {
+		if si.ID != nil && *si.ID == id {
+			found = true
+			continue
+		}
+		acl = append(acl, si)
+	}
+	if !found {
+		return nil
+	}
+
+	_, err = containerClient.SetAccessPolicy(ctx, &container.SetAccessPolicyOptions{
+		Access:       resp.BlobPublicAccess,
+		ContainerACL: acl,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set access policies: %w", err)
+	}
+	return nil
+}
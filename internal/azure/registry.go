@@ -0,0 +1,168 @@
+package azure
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+// Registry hands out one blob client per account for the life of a process.
+// All clients share one HTTP transport, and accounts with the same Entra ID
+// settings share one credential, and with it the credential's token cache.
+type Registry struct {
+	mu          sync.Mutex
+	network     config.NetworkConfig
+	transport   *http.Client
+	retry       policy.RetryOptions
+	clients     map[string]registryEntry
+	credentials map[credentialKey]azcore.TokenCredential
+}
+
+type registryEntry struct {
+	acct   config.AccountConfig
+	client *azblob.Client
+}
+
+// credentialKey identifies the settings a token credential is built from
+type credentialKey struct {
+	authMethod      string
+	tenantID        string
+	clientID        string
+	certificatePath string
+	tokenFilePath   string
+	cloud           string
+	// secret is the secret reference or environment variable, if any
+	secret string
+}
+
+// NewRegistry returns an empty registry with its own HTTP transport, built
+// from the network settings n (nil for the defaults)
+func NewRegistry(n *config.NetworkConfig) (*Registry, error) {
+	var network config.NetworkConfig
+	if n != nil {
+		network = *n
+	}
+	transport, err := newTransport(&network)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := retryOptions(&network)
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{
+		network:     network,
+		transport:   transport,
+		retry:       retry,
+		clients:     make(map[string]registryEntry),
+		credentials: make(map[credentialKey]azcore.TokenCredential),
+	}, nil
+}
+
+var (
+	registryMu sync.Mutex
+	// defaultRegistry serves ClientFor
+	defaultRegistry, _ = NewRegistry(nil)
+)
+
+// Configure applies network settings to every client created afterwards by
+// ClientFor. Settings equal to the current ones keep the cached clients.
+func Configure(n *config.NetworkConfig) error {
+	var network config.NetworkConfig
+	if n != nil {
+		network = *n
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if reflect.DeepEqual(network, defaultRegistry.network) {
+		return nil
+	}
+	r, err := NewRegistry(&network)
+	if err != nil {
+		return err
+	}
+	defaultRegistry = r
+	return nil
+}
+
+func currentRegistry() *Registry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return defaultRegistry
+}
+
+// ClientFor returns the process-wide client of account name
+func ClientFor(name string, acct *config.AccountConfig) (*azblob.Client, error) {
+	return currentRegistry().Client(name, acct)
+}
+
+// Client returns the cached client of account name, creating it on first
+// use or when the account's settings changed
+func (r *Registry) Client(name string, acct *config.AccountConfig) (*azblob.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.clients[name]; ok && entry.acct == *acct {
+		return entry.client, nil
+	}
+
+	logger.Debug("creating client", "account", name, "auth_method", acct.AuthMethod, "service_url", RedactURL(acct.ServiceURL))
+	var client *azblob.Client
+	var err error
+	if IsTokenAuth(acct.AuthMethod) {
+		var cred azcore.TokenCredential
+		if cred, err = r.credential(acct); err == nil {
+			client, err = azblob.NewClient(acct.ServiceURL, cred, r.clientOptions())
+		}
+	} else {
+		client, err = newClient(acct, storedSecret(acct), r.clientOptions())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.clients[name] = registryEntry{acct: *acct, client: client}
+	return client, nil
+}
+
+// credential returns the shared token credential for acct's settings
+func (r *Registry) credential(acct *config.AccountConfig) (azcore.TokenCredential, error) {
+	key := credentialKey{
+		authMethod:      acct.AuthMethod,
+		tenantID:        acct.TenantID,
+		clientID:        acct.ClientID,
+		certificatePath: acct.CertificatePath,
+		tokenFilePath:   acct.TokenFilePath,
+		cloud:           acct.Cloud,
+		secret:          acct.SecretRef,
+	}
+	if key.secret == "" {
+		if suffix := SecretEnvSuffix(acct.AuthMethod); suffix != "" {
+			key.secret = SecretEnvName(acct, suffix)
+		}
+	}
+	if cred, ok := r.credentials[key]; ok {
+		return cred, nil
+	}
+
+	cred, err := newTokenCredential(acct, storedSecret(acct), r.clientOptions().ClientOptions)
+	if err != nil {
+		return nil, err
+	}
+	r.credentials[key] = cred
+	return cred, nil
+}
+
+func (r *Registry) clientOptions() *azblob.ClientOptions {
+	return &azblob.ClientOptions{ClientOptions: policy.ClientOptions{
+		Transport:        r.transport,
+		Retry:            r.retry,
+		PerRetryPolicies: []policy.Policy{logPolicy{}},
+	}}
+}
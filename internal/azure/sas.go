@@ -0,0 +1,131 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+// SASProtocol returns the protocols a SAS for serviceURL must allow for its
+// links to work: "https", or "https,http" for plain-HTTP endpoints such as
+// the storage emulator
+func SASProtocol(serviceURL string) string {
+	if strings.HasPrefix(serviceURL, "http://") {
+		return "https,http"
+	}
+	return "https"
+}
+
+// SASOptions describes a service SAS for a container or blob
+type SASOptions struct {
+	Container   string
+	BlobName    string // empty for a container SAS
+	VersionID   string
+	Permissions string // e.g. "rl"; may be empty when Policy defines it
+	Expiry      time.Time
+	IPRange     string // single IP or "start-end"
+	Protocol    string // "https" or "https,http"
+	Policy      string // stored access policy identifier
+}
+
+// GenerateSASURL signs a service SAS and returns the full resource URL.
+// Shared-key and connection-string accounts sign with the account key,
+// Entra ID accounts sign with a user delegation key.
+func GenerateSASURL(ctx context.Context, acct *config.AccountConfig, client *azblob.Client, opts SASOptions) (string, error) {
+	values := sas.BlobSignatureValues{
+		Protocol:      sas.Protocol(opts.Protocol),
+		ExpiryTime:    opts.Expiry,
+		Permissions:   opts.Permissions,
+		Identifier:    opts.Policy,
+		ContainerName: opts.Container,
+		BlobName:      opts.BlobName,
+		BlobVersion:   opts.VersionID,
+	}
+	if opts.IPRange != "" {
+		ipRange, err := parseIPRange(opts.IPRange)
+		if err != nil {
+			return "", err
+		}
+		values.IPRange = ipRange
+	}
+
+	var qp sas.QueryParameters
+	switch acct.AuthMethod {
+	case "shared-key", "connection-string", "emulator":
+		cred, err := NewSharedKeyCredential(acct)
+		if err != nil {
+			return "", err
+		}
+		qp, err = values.SignWithSharedKey(cred)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign SAS: %w", err)
+		}
+	default:
+		if !IsTokenAuth(acct.AuthMethod) {
+			return "", fmt.Errorf("cannot generate SAS for auth method: %s", acct.AuthMethod)
+		}
+		if opts.Policy != "" {
+			return "", fmt.Errorf("stored access policies cannot be used with user delegation SAS; use a shared-key account")
+		}
+		// Start slightly in the past to tolerate clock skew
+		start := time.Now().UTC().Add(-5 * time.Minute)
+		expiry := opts.Expiry.UTC().Format(sas.TimeFormat)
+		udc, err := client.ServiceClient().GetUserDelegationCredential(ctx, service.KeyInfo{
+			Start:  to.Ptr(start.Format(sas.TimeFormat)),
+			Expiry: &expiry,
+		}, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get user delegation key: %w", err)
+		}
+		values.StartTime = start
+		qp, err = values.SignWithUserDelegation(udc)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign SAS: %w", err)
+		}
+	}
+
+	containerClient := client.ServiceClient().NewContainerClient(opts.Container)
+	resourceURL := containerClient.URL()
+	if opts.BlobName != "" {
+		blobClient := containerClient.NewBlobClient(opts.BlobName)
+		if opts.VersionID != "" {
+			// The token is signed for the version, so the link must name it
+			versioned, err := blobClient.WithVersionID(opts.VersionID)
+			if err != nil {
+				return "", fmt.Errorf("invalid version ID: %w", err)
+			}
+			blobClient = versioned
+		}
+		resourceURL = blobClient.URL()
+	}
+	sep := "?"
+	if strings.Contains(resourceURL, "?") {
+		sep = "&"
+	}
+	return resourceURL + sep + qp.Encode(), nil
+}
+
+func parseIPRange(s string) (sas.IPRange, error) {
+	startStr, endStr, hasEnd := strings.Cut(s, "-")
+	start := net.ParseIP(strings.TrimSpace(startStr))
+	if start == nil {
+		return sas.IPRange{}, fmt.Errorf("invalid IP address: %s", startStr)
+	}
+	ipRange := sas.IPRange{Start: start}
+	if hasEnd {
+		end := net.ParseIP(strings.TrimSpace(endStr))
+		if end == nil {
+			return sas.IPRange{}, fmt.Errorf("invalid IP address: %s", endStr)
+		}
+		ipRange.End = end
+	}
+	return ipRange, nil
+}
@@ -0,0 +1,53 @@
+package azure
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+func TestSASProtocol(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://acct.blob.core.windows.net/":     "https",
+		"http://127.0.0.1:10000/devstoreaccount1": "https,http",
+	} {
+		if got := SASProtocol(url); got != want {
+			t.Errorf("SASProtocol(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestGenerateSASURLVersion(t *testing.T) {
+	acct := &config.AccountConfig{AuthMethod: "emulator", ServiceURL: EmulatorServiceURL(""), AccountName: EmulatorAccountName}
+	client, err := ClientFor("sas-test", acct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := "2024-01-01T00:00:00.0000000Z"
+	link, err := GenerateSASURL(context.Background(), acct, client, SASOptions{
+		Container:   "data",
+		BlobName:    "f.txt",
+		VersionID:   version,
+		Permissions: "r",
+		Expiry:      time.Now().Add(time.Hour),
+		Protocol:    "https,http",
+	})
+	if err != nil {
+		t.Fatalf("GenerateSASURL: %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("versionid") != version || q.Get("sr") != "bv" || q.Get("sig") == "" {
+		t.Errorf("link %s does not name version %s", link, version)
+	}
+	if strings.Count(link, "?") != 1 {
+		t.Errorf("link %s has more than one query", link)
+	}
+}
@@ -0,0 +1,207 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// traceFile collects HTTP exchanges in HAR format while a trace is active
+type traceFile struct {
+	mu      sync.Mutex
+	file    *os.File
+	creator harCreator
+	entries []harEntry
+}
+
+var (
+	traceMu sync.Mutex
+	trace   *traceFile
+)
+
+// HAR 1.2 subset; fields prefixed with _ are custom, as the format allows
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	QueryString []harHeader `json:"queryString"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	// Text is only kept for error responses
+	Text string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// StartTrace records every HTTP exchange until StopTrace writes them to path
+// as HAR-like JSON. Secrets are redacted as in the logs; bodies are only
+// kept for error responses.
+func StartTrace(path, version string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file: %w", err)
+	}
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	trace = &traceFile{file: f, creator: harCreator{Name: "azbutils", Version: version}}
+	return nil
+}
+
+// StopTrace writes the recorded exchanges, if a trace is active
+func StopTrace() error {
+	traceMu.Lock()
+	t := trace
+	trace = nil
+	traceMu.Unlock()
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var doc harLog
+	doc.Log.Version = "1.2"
+	doc.Log.Creator = t.creator
+	doc.Log.Entries = t.entries
+	if doc.Log.Entries == nil {
+		doc.Log.Entries = []harEntry{}
+	}
+
+	enc := json.NewEncoder(t.file)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(doc)
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	return nil
+}
+
+// recordExchange adds one HTTP attempt to the active trace
+func recordExchange(req *http.Request, resp *http.Response, body []byte, start time.Time, elapsed time.Duration, err error) {
+	traceMu.Lock()
+	t := trace
+	traceMu.Unlock()
+	if t == nil {
+		return
+	}
+
+	ms := float64(elapsed.Microseconds()) / 1000
+	entry := harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         RedactURL(req.URL.String()),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req.URL),
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+		Response: harResponse{HeadersSize: -1, BodySize: -1},
+		Timings:  harTimings{Send: 0, Wait: ms, Receive: 0},
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if resp != nil {
+		entry.Response = harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Headers:     harHeaders(resp.Header),
+			Content: harContent{
+				Size:     resp.ContentLength,
+				MimeType: resp.Header.Get("Content-Type"),
+				Text:     string(body),
+			},
+			HeadersSize: -1,
+			BodySize:    resp.ContentLength,
+		}
+	}
+
+	t.mu.Lock()
+	t.entries = append(t.entries, entry)
+	t.mu.Unlock()
+}
+
+func harHeaders(h http.Header) []harHeader {
+	headers := make([]harHeader, 0, len(h))
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[name] {
+			headers = append(headers, harHeader{Name: name, Value: redactHeader(name, v)})
+		}
+	}
+	return headers
+}
+
+func harQuery(u *url.URL) []harHeader {
+	query := make([]harHeader, 0)
+	values := u.Query()
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		for _, v := range values[name] {
+			if name == "sig" {
+				v = redacted
+			}
+			query = append(query, harHeader{Name: name, Value: v})
+		}
+	}
+	return query
+}
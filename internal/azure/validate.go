@@ -0,0 +1,131 @@
+package azure
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+var storageAccountPattern = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+
+// ValidateAccountName checks the storage account naming rules
+func ValidateAccountName(name string) error {
+	if !storageAccountPattern.MatchString(name) {
+		return fmt.Errorf("invalid storage account name '%s': use 3-24 lowercase letters and digits", name)
+	}
+	return nil
+}
+
+// ValidateServiceURL checks that s is an absolute http(s) URL
+func ValidateServiceURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid service URL '%s': expected https://<account>.blob.<suffix>", s)
+	}
+	return nil
+}
+
+// ValidateSASURL checks that s is a service URL carrying a SAS signature
+func ValidateSASURL(s string) error {
+	if err := ValidateServiceURL(s); err != nil {
+		return err
+	}
+	u, _ := url.Parse(s)
+	if !u.Query().Has("sig") {
+		return fmt.Errorf("SAS URL has no signature (sig=...)")
+	}
+	return nil
+}
+
+// ValidateAccountKey checks that an account key is valid base64
+func ValidateAccountKey(key string) error {
+	if _, err := base64.StdEncoding.DecodeString(key); err != nil {
+		return fmt.Errorf("account key is not valid base64")
+	}
+	return nil
+}
+
+// ParseConnectionString splits a storage connection string into its settings
+// and checks that it can reach a blob endpoint
+func ParseConnectionString(cs string) (map[string]string, error) {
+	parts := make(map[string]string)
+	for _, kv := range strings.Split(cs, ";") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed connection string: '%s' is not a key=value pair", kv)
+		}
+		parts[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	if parts["UseDevelopmentStorage"] == "true" {
+		return parts, nil
+	}
+	if parts["AccountName"] == "" && parts["BlobEndpoint"] == "" {
+		return nil, fmt.Errorf("malformed connection string: missing AccountName or BlobEndpoint")
+	}
+	if parts["AccountKey"] == "" && parts["SharedAccessSignature"] == "" {
+		return nil, fmt.Errorf("malformed connection string: missing AccountKey or SharedAccessSignature")
+	}
+	if key := parts["AccountKey"]; key != "" {
+		if err := ValidateAccountKey(key); err != nil {
+			return nil, fmt.Errorf("malformed connection string: %w", err)
+		}
+	}
+	return parts, nil
+}
+
+// ValidateAccount checks an account's settings before it is saved. value is a
+// secret that is about to be stored; it may be empty when the stored secret is kept.
+func ValidateAccount(acct *config.AccountConfig, value string) error {
+	if acct.AuthMethod != "emulator" && !slices.Contains(AuthMethods, acct.AuthMethod) {
+		return fmt.Errorf("unknown auth method '%s' (expected one of: %s)", acct.AuthMethod, strings.Join(AuthMethods, ", "))
+	}
+	if err := ValidateCloud(acct.Cloud); err != nil {
+		return err
+	}
+	if acct.AuthMethod != "sas" {
+		if err := ValidateServiceURL(acct.ServiceURL); err != nil {
+			return err
+		}
+	}
+	// The account name only has to follow the storage rules when it is used
+	// to sign requests or to build the service URL
+	if acct.AuthMethod == "shared-key" || acct.ServiceURL == DefaultServiceURL(acct.AccountName, acct) {
+		if err := ValidateAccountName(acct.AccountName); err != nil {
+			return err
+		}
+	}
+
+	switch acct.AuthMethod {
+	case "connection-string":
+		if value != "" {
+			if _, err := ParseConnectionString(value); err != nil {
+				return err
+			}
+		}
+	case "shared-key":
+		if value != "" {
+			return ValidateAccountKey(value)
+		}
+	case "sas":
+		if value != "" {
+			return ValidateSASURL(value)
+		}
+	case "service-principal-secret":
+		return requireIdentity(acct)
+	case "service-principal-cert":
+		if acct.CertificatePath == "" {
+			return fmt.Errorf("service-principal-cert requires a certificate path")
+		}
+		return requireIdentity(acct)
+	}
+	return nil
+}
@@ -2,60 +2,383 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 )
 
 type AccountConfig struct {
 	AuthMethod  string `json:"auth_method"`
 	ServiceURL  string `json:"service_url,omitempty"`
 	AccountName string `json:"account_name,omitempty"`
-	AccountKey  string `json:"account_key,omitempty"`
-	Connection  string `json:"connection,omitempty"`
+	// SecretRef points at the account key, connection string or SAS URL in a
+	// secret store (e.g. "keychain:myaccount"). When empty the secret is read
+	// from <ACCOUNT>_ACCOUNT_KEY, _CONNECTION_STRING or _SAS_URL.
+	SecretRef string `json:"secret_ref,omitempty"`
+	// Microsoft Entra ID settings for the explicit credential auth methods
+	TenantID        string `json:"tenant_id,omitempty"`
+	ClientID        string `json:"client_id,omitempty"`
+	CertificatePath string `json:"certificate_path,omitempty"`
+	TokenFilePath   string `json:"token_file_path,omitempty"`
+	// Cloud is "public" (default), "usgovernment" or "china". EndpointSuffix
+	// overrides the cloud's storage suffix (e.g. core.windows.net).
+	Cloud          string `json:"cloud,omitempty"`
+	EndpointSuffix string `json:"endpoint_suffix,omitempty"`
 }
 
 type Config struct {
+	// Version is the schema version; older files are migrated on load
+	Version        int                       `json:"version"`
 	DefaultAccount string                    `json:"default_account"`
 	Accounts       map[string]*AccountConfig `json:"accounts"`
+	// Aliases map short names to path prefixes, used as "@name/rest/of/path"
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Network tunes retries, timeouts and the HTTP transport of every client
+	Network *NetworkConfig `json:"network,omitempty"`
 }
 
+// EnvPath names the environment variable that overrides the config file location
+const EnvPath = "AZBUTILS_CONFIG"
+
+// ProjectFile is the name of the project-local config overlay
+const ProjectFile = ".azbutils.json"
+
+// pathOverride is set from the --config flag and wins over EnvPath
+var pathOverride string
+
+// SetPath overrides the config file location for this process
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// Dir returns the directory holding the config file, creating it if needed.
+// Other local state (secrets, share ledger) lives next to the config file.
+func Dir() (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(path), nil
+}
+
+// ConfigPath resolves the user config file: --config, then $AZBUTILS_CONFIG,
+// then $XDG_CONFIG_HOME/azbutils/config.json (~/.config/azbutils/config.json)
 func ConfigPath() (string, error) {
+	path := pathOverride
+	if path == "" {
+		path = os.Getenv(EnvPath)
+	}
+	if path == "" {
+		dir, err := defaultDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, "config.json")
+		if err := migrateLegacy(dir); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create config dir: %w", err)
+	}
+	return path, nil
+}
+
+func defaultDir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "azbutils"), nil
+	}
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine config directory: %w", err)
+		}
+		return filepath.Join(dir, "azbutils"), nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("cannot determine home directory: %w", err)
 	}
-	dir := filepath.Join(home, ".azbutil")
+	return filepath.Join(home, ".config", "azbutils"), nil
+}
+
+// migrateLegacy moves files from the pre-XDG ~/.azbutil directory into dir,
+// unless dir already has a config
+func migrateLegacy(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); err == nil {
+		return nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	legacyDir := filepath.Join(home, ".azbutil")
+	if _, err := os.Stat(filepath.Join(legacyDir, "config.json")); err != nil {
+		return nil
+	}
+
 	if err := os.MkdirAll(dir, 0700); err != nil {
-		return "", fmt.Errorf("failed to create config dir: %w", err)
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+	for _, name := range []string{"config.json", "secrets.enc", "shares.json"} {
+		src := filepath.Join(legacyDir, name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := moveFile(src, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", src, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Migrated config from %s to %s\n", legacyDir, dir)
+	return nil
+}
+
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	// Rename fails across filesystems; fall back to copy and remove
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, 0600); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// ProjectPath finds the nearest .azbutils.json in the working directory or
+// its parents, returning "" if there is none
+func ProjectPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
 	}
-	return filepath.Join(dir, "config.json"), nil
 }
 
+// Load returns the user config with the project-local config overlaid on it,
+// and the network flags on top. Use LoadUser or Update when the result will
+// be saved.
 func Load() (*Config, error) {
 	path, err := ConfigPath()
 	if err != nil {
 		return nil, err
 	}
-	data, err := os.ReadFile(path)
+	return LoadFrom(path)
+}
+
+// LoadFrom is Load for the user config file at path
+func LoadFrom(path string) (*Config, error) {
+	cfg, err := loadUser(path)
+	if err != nil {
+		return nil, err
+	}
+	if projectPath := ProjectPath(); projectPath != "" {
+		project, _, err := readFile(projectPath, false)
+		if err != nil {
+			return nil, err
+		}
+		cfg.overlay(project, projectPath)
+	}
+	cfg.mergeNetwork(networkOverride)
+	return cfg, nil
+}
+
+// LoadUser returns the user config without any project overlay. A missing
+// file yields an empty config; an outdated one is migrated and written back.
+func LoadUser() (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadUser(path)
+}
+
+func loadUser(path string) (*Config, error) {
+	cfg, migrated, err := readFile(path, true)
 	if err != nil {
 		return nil, err
 	}
+	if migrated {
+		err := WithLock(path, func() error { return write(path, cfg) })
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Migrated config %s to version %d\n", path, CurrentVersion)
+	}
+	return cfg, nil
+}
+
+// Update loads the user config, applies fn and saves the result while holding
+// the config lock, so concurrent invocations do not lose each other's changes.
+// Nothing is written if fn returns an error.
+func Update(fn func(cfg *Config) error) error {
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	return WithLock(path, func() error {
+		cfg, _, err := readFile(path, true)
+		if err != nil {
+			return err
+		}
+		if err := fn(cfg); err != nil {
+			return err
+		}
+		return write(path, cfg)
+	})
+}
+
+// Parse decodes and migrates config data read from source
+func Parse(data []byte, source string) (*Config, error) {
+	cfg, _, err := decode(data, source, false)
+	return cfg, err
+}
+
+func newConfig() *Config {
+	return &Config{Version: CurrentVersion, Accounts: make(map[string]*AccountConfig)}
+}
+
+// readFile loads a config file, reporting whether it was migrated. user marks
+// the user config, whose migrations may move secrets into a secret store.
+func readFile(path string, user bool) (*Config, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return newConfig(), false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	return decode(data, path, user)
+}
+
+func decode(data []byte, source string, user bool) (*Config, bool, error) {
+	doc := make(map[string]any)
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, fmt.Errorf("invalid config %s: %w", source, err)
+	}
+	migrated, err := migrate(&document{source: source, user: user, data: doc})
+	if err != nil {
+		return nil, false, err
+	}
+	if migrated {
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, false, fmt.Errorf("failed to serialize config: %w", err)
+		}
+	}
+
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+		return nil, false, fmt.Errorf("invalid config %s: %w", source, err)
 	}
-	return &cfg, nil
+	if cfg.Accounts == nil {
+		cfg.Accounts = make(map[string]*AccountConfig)
+	}
+	return &cfg, migrated, nil
 }
 
+// overlay applies a project config on top of c; project entries win. A
+// project file comes with whatever repository is checked out, so it may only
+// name things: accounts, with their endpoints and credentials, and network
+// settings such as the proxy and CA bundle stay in the user config and
+// flags, where a cloned repository cannot redirect them.
+func (c *Config) overlay(project *Config, source string) {
+	var ignored []string
+	if len(project.Accounts) > 0 {
+		ignored = append(ignored, "accounts")
+	}
+	if project.Network != nil && !reflect.ValueOf(*project.Network).IsZero() {
+		ignored = append(ignored, "network settings")
+	}
+	if len(ignored) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring the %s in %s; a project config can only set aliases and default_account\n", strings.Join(ignored, " and "), source)
+	}
+	if project.DefaultAccount != "" {
+		c.DefaultAccount = project.DefaultAccount
+	}
+	if len(project.Aliases) > 0 && c.Aliases == nil {
+		c.Aliases = make(map[string]string)
+	}
+	for name, target := range project.Aliases {
+		c.Aliases[name] = target
+	}
+}
+
+// Save writes cfg to the user config file. Configs from Load include the
+// project overlay, so modify one returned by LoadUser instead.
 func Save(cfg *Config) error {
 	path, err := ConfigPath()
 	if err != nil {
 		return err
 	}
+	return WithLock(path, func() error { return write(path, cfg) })
+}
+
+// write replaces the config file atomically: readers see either the old or
+// the new file, never a partial one
+func write(path string, cfg *Config) error {
+	cfg.Version = CurrentVersion
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to serialize config: %w", err)
 	}
-	return os.WriteFile(path, data, 0600)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil && runtime.GOOS != "windows" {
+		tmp.Close()
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// WithLock runs fn while holding an exclusive lock on path's lock file, so
+// read-modify-write cycles of files in the config dir do not interleave
+func WithLock(path string, fn func() error) error {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open lock for %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+	}
+	defer unlockFile(f)
+	return fn()
 }
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestOverlayKeepsUserAccountsAndNetwork(t *testing.T) {
+	user := newConfig()
+	user.Accounts["prod"] = &AccountConfig{ServiceURL: "https://prod.blob.core.windows.net"}
+	user.Network = &NetworkConfig{Proxy: "direct"}
+
+	project := newConfig()
+	project.DefaultAccount = "prod"
+	project.Aliases = map[string]string{"logs": "az://prod//logs/"}
+	project.Accounts["prod"] = &AccountConfig{ServiceURL: "https://attacker.blob.core.windows.net"}
+	project.Accounts["extra"] = &AccountConfig{ServiceURL: "https://extra.blob.core.windows.net"}
+	project.Network = &NetworkConfig{Proxy: "http://attacker.example.com:8080", CABundle: "attacker.pem"}
+
+	user.overlay(project, ".azbutils.json")
+
+	if got := user.Accounts["prod"].ServiceURL; got != "https://prod.blob.core.windows.net" {
+		t.Errorf("prod service URL = %q, want the user's", got)
+	}
+	if _, ok := user.Accounts["extra"]; ok {
+		t.Error("project account 'extra' was added")
+	}
+	if user.Network.Proxy != "direct" || user.Network.CABundle != "" {
+		t.Errorf("network = %+v, want the user's", user.Network)
+	}
+	if user.DefaultAccount != "prod" || user.Aliases["logs"] != "az://prod//logs/" {
+		t.Errorf("default account %q, aliases %v; want the project's", user.DefaultAccount, user.Aliases)
+	}
+}
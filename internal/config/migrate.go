@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// CurrentVersion is the config schema version written by this build
+const CurrentVersion = 1
+
+// MigrateSecret moves a plaintext secret found in an old user config into a
+// secret store and returns its reference. It is set by the CLI, since the
+// secret stores depend on this package.
+var MigrateSecret func(name, value string) (string, error)
+
+// document is a raw config file being migrated
+type document struct {
+	source string
+	// user is set for the user config; project files are never written back
+	user bool
+	data map[string]any
+}
+
+// migrations[i] upgrades a document from version i to i+1
+var migrations = []func(doc *document) error{
+	migrateV0,
+}
+
+// migrate upgrades doc to CurrentVersion, reporting whether anything changed
+func migrate(doc *document) (bool, error) {
+	version := 0
+	if v, ok := doc.data["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > CurrentVersion {
+		return false, fmt.Errorf("config %s has version %d, newer than this azbutils supports (%d); please upgrade", doc.source, version, CurrentVersion)
+	}
+
+	migrated := false
+	for ; version < CurrentVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return false, fmt.Errorf("failed to migrate config %s from version %d: %w", doc.source, version, err)
+		}
+		doc.data["version"] = version + 1
+		migrated = true
+	}
+	return migrated, nil
+}
+
+// migrateV0 moves the plaintext account_key and connection fields of
+// unversioned configs, and SAS tokens kept in service_url, into a secret
+// store. If a secret of the user config cannot be moved, the migration fails
+// so that the file keeps its only copy. An account keeps one secret: the one
+// its auth method uses is moved and any other is removed with a warning.
+// Project files are never written back; their secrets are dropped in memory only.
+func migrateV0(doc *document) error {
+	accounts, _ := doc.data["accounts"].(map[string]any)
+	for name, raw := range accounts {
+		acct, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, s := range plaintextSecrets(acct) {
+			if ref, _ := acct["secret_ref"].(string); ref != "" {
+				s.remove()
+				fmt.Fprintf(os.Stderr, "Warning: removed the plaintext %s of account '%s' from %s; an account keeps a single secret, the one its auth method uses\n", s.field, name, doc.source)
+				continue
+			}
+			if !doc.user {
+				s.remove()
+				fmt.Fprintf(os.Stderr, "Warning: ignoring the plaintext secret of account '%s' in %s; keep secrets in the user config\n", name, doc.source)
+				continue
+			}
+			if MigrateSecret == nil {
+				return fmt.Errorf("account '%s' has a plaintext secret; run the azbutils CLI once to move it to a secret store", name)
+			}
+			ref, err := MigrateSecret(name, s.value)
+			if err != nil {
+				return fmt.Errorf("could not move the plaintext secret of account '%s' to a secret store, so the config was left unchanged: %w", name, err)
+			}
+			acct["secret_ref"] = ref
+			s.remove()
+		}
+	}
+	return nil
+}
+
+// plaintextSecret is a secret stored in a v0 account, with the change that
+// takes it out of the account
+type plaintextSecret struct {
+	field  string
+	value  string
+	used   bool
+	remove func()
+}
+
+// plaintextSecrets returns the secrets of a v0 account, the one its auth
+// method uses first, so that it is the one moved to the secret store
+func plaintextSecrets(acct map[string]any) []plaintextSecret {
+	method, _ := acct["auth_method"].(string)
+	var secrets []plaintextSecret
+	for field, usedBy := range map[string]string{"account_key": "shared-key", "connection": "connection-string"} {
+		value, _ := acct[field].(string)
+		if value == "" {
+			delete(acct, field)
+			continue
+		}
+		secrets = append(secrets, plaintextSecret{field: field, value: value, used: method == usedBy, remove: func() { delete(acct, field) }})
+	}
+
+	// SAS accounts kept the whole SAS URL, token included, as service_url
+	if method == "sas" {
+		serviceURL, _ := acct["service_url"].(string)
+		if base, query, ok := strings.Cut(serviceURL, "?"); ok && query != "" {
+			secrets = append(secrets, plaintextSecret{field: "SAS token", value: serviceURL, used: true, remove: func() { acct["service_url"] = strings.TrimSuffix(base, "/") }})
+		}
+	}
+	slices.SortStableFunc(secrets, func(a, b plaintextSecret) int {
+		switch {
+		case a.used == b.used:
+			return strings.Compare(a.field, b.field)
+		case a.used:
+			return -1
+		default:
+			return 1
+		}
+	})
+	return secrets
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestMigrateV0KeepsTheSecretOfTheAuthMethod(t *testing.T) {
+	var moved []string
+	MigrateSecret = func(name, value string) (string, error) {
+		moved = append(moved, value)
+		return "file:" + name, nil
+	}
+	t.Cleanup(func() { MigrateSecret = nil })
+
+	acct := map[string]any{
+		"auth_method": "connection-string",
+		"account_key": "key",
+		"connection":  "DefaultEndpointsProtocol=https;AccountName=a;AccountKey=key",
+	}
+	doc := &document{source: "config.json", user: true, data: map[string]any{
+		"accounts": map[string]any{"a": acct},
+	}}
+	if err := migrateV0(doc); err != nil {
+		t.Fatalf("migrateV0: %v", err)
+	}
+	if len(moved) != 1 || moved[0] != "DefaultEndpointsProtocol=https;AccountName=a;AccountKey=key" {
+		t.Errorf("moved %q, want only the connection string", moved)
+	}
+	if acct["secret_ref"] != "file:a" || acct["account_key"] != nil || acct["connection"] != nil {
+		t.Errorf("account = %v, want only a secret_ref", acct)
+	}
+}
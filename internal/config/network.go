@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Operation classes with their own timeout
+const (
+	// OpList covers listings and the bulk deletes and undeletes built on them
+	OpList = "list"
+	// OpTransfer covers uploads, downloads and server-side copies
+	OpTransfer = "transfer"
+	// OpDefault covers everything else, e.g. connection tests and leases
+	OpDefault = "default"
+)
+
+// defaultTimeouts apply when neither the config nor a flag sets a timeout.
+// Listings and transfers run as long as they make progress; TryTimeout and
+// the retry policy deal with stalled requests.
+var defaultTimeouts = map[string]time.Duration{
+	OpList:     0,
+	OpTransfer: 0,
+	OpDefault:  5 * time.Minute,
+}
+
+// NetworkConfig tunes the retry policy, timeouts and HTTP transport shared by
+// every client. Durations are Go duration strings such as "800ms" or "5m";
+// empty values keep the defaults.
+type NetworkConfig struct {
+	// MaxRetries is the number of retries after the first try: 0 keeps the
+	// SDK default (3) and -1 disables retries
+	MaxRetries    int32  `json:"max_retries,omitempty"`
+	RetryDelay    string `json:"retry_delay,omitempty"`
+	MaxRetryDelay string `json:"max_retry_delay,omitempty"`
+	// RetryStatusCodes replaces the HTTP status codes that are retried
+	RetryStatusCodes []int `json:"retry_status_codes,omitempty"`
+	// TryTimeout bounds a single HTTP attempt
+	TryTimeout string `json:"try_timeout,omitempty"`
+	// Timeouts bound whole operations; "0" means no timeout
+	Timeouts Timeouts `json:"timeouts,omitzero"`
+	// Proxy is a proxy URL, or "direct" to ignore the HTTPS_PROXY and
+	// HTTP_PROXY environment variables used by default
+	Proxy string `json:"proxy,omitempty"`
+	// CABundle is a PEM file of certificates trusted besides the system roots
+	CABundle string      `json:"ca_bundle,omitempty"`
+	HTTP2    HTTP2Config `json:"http2,omitzero"`
+}
+
+// Timeouts holds the timeout of each operation class
+type Timeouts struct {
+	List     string `json:"list,omitempty"`
+	Transfer string `json:"transfer,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// HTTP2Config tunes HTTP/2 connections
+type HTTP2Config struct {
+	// Disable restricts connections to HTTP/1.1
+	Disable bool `json:"disable,omitempty"`
+	// ReadIdleTimeout sends a health check ping on connections that
+	// received nothing for this long; PingTimeout closes them when the
+	// ping goes unanswered
+	ReadIdleTimeout  string `json:"read_idle_timeout,omitempty"`
+	PingTimeout      string `json:"ping_timeout,omitempty"`
+	MaxReadFrameSize int    `json:"max_read_frame_size,omitempty"`
+}
+
+// networkOverride is set from the command-line flags and wins over the
+// config files
+var networkOverride *NetworkConfig
+
+// SetNetworkOverride makes Load apply n on top of the configured network
+// settings for this process
+func SetNetworkOverride(n *NetworkConfig) {
+	networkOverride = n
+}
+
+// ParseDuration parses the duration setting name; an empty value yields 0
+func ParseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s': %w", name, value, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s '%s': must not be negative", name, value)
+	}
+	return d, nil
+}
+
+// Timeout returns the timeout of operation class op, 0 meaning none. n may
+// be nil.
+func (n *NetworkConfig) Timeout(op string) (time.Duration, error) {
+	var value string
+	if n != nil {
+		switch op {
+		case OpList:
+			value = n.Timeouts.List
+		case OpTransfer:
+			value = n.Timeouts.Transfer
+		default:
+			op, value = OpDefault, n.Timeouts.Default
+		}
+	}
+	if value == "" {
+		return defaultTimeouts[op], nil
+	}
+	return ParseDuration(op+" timeout", value)
+}
+
+// Validate checks the settings without touching the network. n may be nil.
+func (n *NetworkConfig) Validate() error {
+	if n == nil {
+		return nil
+	}
+	if n.MaxRetries < -1 {
+		return fmt.Errorf("invalid max_retries %d: use -1 to disable retries", n.MaxRetries)
+	}
+	durations := map[string]string{
+		"retry_delay":             n.RetryDelay,
+		"max_retry_delay":         n.MaxRetryDelay,
+		"try_timeout":             n.TryTimeout,
+		"http2.read_idle_timeout": n.HTTP2.ReadIdleTimeout,
+		"http2.ping_timeout":      n.HTTP2.PingTimeout,
+	}
+	for name, value := range durations {
+		if _, err := ParseDuration(name, value); err != nil {
+			return err
+		}
+	}
+	for _, op := range []string{OpList, OpTransfer, OpDefault} {
+		if _, err := n.Timeout(op); err != nil {
+			return err
+		}
+	}
+	for _, code := range n.RetryStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid retry status code %d", code)
+		}
+	}
+	if n.Proxy != "" && n.Proxy != "direct" {
+		u, err := url.Parse(n.Proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy '%s': expected a URL such as http://proxy:8080", n.Proxy)
+		}
+	}
+	if n.CABundle != "" {
+		if _, err := os.Stat(n.CABundle); err != nil {
+			return fmt.Errorf("invalid ca_bundle: %w", err)
+		}
+	}
+	if size := n.HTTP2.MaxReadFrameSize; size != 0 && (size < 16<<10 || size > 16<<20) {
+		return fmt.Errorf("invalid http2.max_read_frame_size %d: must be between 16KiB and 16MiB", size)
+	}
+	return nil
+}
+
+// merge applies the set fields of o on top of n
+func (n *NetworkConfig) merge(o *NetworkConfig) {
+	if o.MaxRetries != 0 {
+		n.MaxRetries = o.MaxRetries
+	}
+	mergeString(&n.RetryDelay, o.RetryDelay)
+	mergeString(&n.MaxRetryDelay, o.MaxRetryDelay)
+	if len(o.RetryStatusCodes) > 0 {
+		n.RetryStatusCodes = o.RetryStatusCodes
+	}
+	mergeString(&n.TryTimeout, o.TryTimeout)
+	mergeString(&n.Timeouts.List, o.Timeouts.List)
+	mergeString(&n.Timeouts.Transfer, o.Timeouts.Transfer)
+	mergeString(&n.Timeouts.Default, o.Timeouts.Default)
+	mergeString(&n.Proxy, o.Proxy)
+	mergeString(&n.CABundle, o.CABundle)
+	if o.HTTP2.Disable {
+		n.HTTP2.Disable = true
+	}
+	mergeString(&n.HTTP2.ReadIdleTimeout, o.HTTP2.ReadIdleTimeout)
+	mergeString(&n.HTTP2.PingTimeout, o.HTTP2.PingTimeout)
+	if o.HTTP2.MaxReadFrameSize != 0 {
+		n.HTTP2.MaxReadFrameSize = o.HTTP2.MaxReadFrameSize
+	}
+}
+
+func mergeString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// mergeNetwork applies o on top of c's network settings
+func (c *Config) mergeNetwork(o *NetworkConfig) {
+	if o == nil || reflect.ValueOf(*o).IsZero() {
+		return
+	}
+	if c.Network == nil {
+		c.Network = &NetworkConfig{}
+	}
+	c.Network.merge(o)
+}
@@ -0,0 +1,25 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+)
+
+// envStore reads secrets from environment variables named by the key
+type envStore struct{}
+
+func (envStore) Get(key string) (string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrNotFound, key)
+	}
+	return value, nil
+}
+
+func (envStore) Set(key, value string) error {
+	return fmt.Errorf("environment secrets are read-only; export %s yourself", key)
+}
+
+func (envStore) Delete(key string) error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/orionnectar/go-azbutils/internal/atomicfile"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv names the environment variable holding the file store passphrase
+const PassphraseEnv = "AZBUTILS_SECRET_PASSPHRASE"
+
+// PassphrasePrompt asks for the file store passphrase when PassphraseEnv is
+// unset. The CLI replaces it with an interactive prompt.
+var PassphrasePrompt = func() (string, error) {
+	return "", fmt.Errorf("no passphrase available; set %s", PassphraseEnv)
+}
+
+// fileStore keeps secrets in a passphrase-encrypted file in the config dir.
+// The whole key/value map is sealed with AES-256-GCM under a scrypt-derived key.
+type fileStore struct {
+	path       string
+	passphrase string
+}
+
+type sealedFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func newFileStore() (*fileStore, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return &fileStore{path: filepath.Join(dir, "secrets.enc")}, nil
+}
+
+func (s *fileStore) Get(key string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fileStore) Set(key, value string) error {
+	return config.WithLock(s.path, func() error {
+		secrets, err := s.load()
+		if err != nil {
+			return err
+		}
+		secrets[key] = value
+		return s.save(secrets)
+	})
+}
+
+func (s *fileStore) Delete(key string) error {
+	return config.WithLock(s.path, func() error {
+		secrets, err := s.load()
+		if err != nil {
+			return err
+		}
+		if _, ok := secrets[key]; !ok {
+			return nil
+		}
+		delete(secrets, key)
+		return s.save(secrets)
+	})
+}
+
+func (s *fileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sealed sealedFile
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("invalid secrets file: %w", err)
+	}
+	gcm, err := s.cipher(sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, sealed.Nonce, sealed.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file (wrong passphrase?)")
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid secrets file: %w", err)
+	}
+	return secrets, nil
+}
+
+func (s *fileStore) save(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	sealed := sealedFile{Salt: make([]byte, 16)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return err
+	}
+	gcm, err := s.cipher(sealed.Salt)
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return err
+	}
+	sealed.Data = gcm.Seal(nil, sealed.Nonce, plain, nil)
+
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.path, 0600, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func (s *fileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if s.passphrase == "" {
+		s.passphrase = os.Getenv(PassphraseEnv)
+	}
+	if s.passphrase == "" {
+		p, err := PassphrasePrompt()
+		if err != nil {
+			return nil, err
+		}
+		s.passphrase = p
+	}
+
+	key, err := scrypt.Key([]byte(s.passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,47 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainStore keeps secrets in the macOS Keychain via the security tool
+type keychainStore struct{}
+
+func (keychainStore) Get(key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", serviceName, "-a", key, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("keychain unavailable: %w", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (keychainStore) Set(key, value string) error {
+	// Commands are fed through stdin so the secret never shows up in the process list
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		keychainQuote(serviceName), keychainQuote(key), keychainQuote(value)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write keychain item: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (keychainStore) Delete(key string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", serviceName, "-a", key).Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("keychain unavailable: %w", err)
+	}
+	return nil
+}
+
+func keychainQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
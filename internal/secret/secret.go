@@ -0,0 +1,93 @@
+// Package secret stores account credentials outside of the config file.
+//
+// Config entries hold a reference of the form "<backend>:<key>", for example
+// "keychain:myaccount" or "env:MYACCOUNT_ACCOUNT_KEY", which is resolved to
+// the secret value when a client is created.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// ErrNotFound is returned when a secret does not exist in its store
+var ErrNotFound = errors.New("secret not found")
+
+// Store reads and writes secrets by key
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Backend names usable in references
+const (
+	BackendEnv           = "env"
+	BackendFile          = "file"
+	BackendKeychain      = "keychain"
+	BackendSecretService = "secret-service"
+)
+
+// serviceName labels azbutils entries in OS keyrings
+const serviceName = "azbutils"
+
+// Backends lists the backends usable on this platform, preferred first
+func Backends() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{BackendKeychain, BackendFile, BackendEnv}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{BackendSecretService, BackendFile, BackendEnv}
+	default:
+		return []string{BackendFile, BackendEnv}
+	}
+}
+
+// Open returns the store for a backend name
+func Open(backend string) (Store, error) {
+	switch backend {
+	case BackendEnv:
+		return envStore{}, nil
+	case BackendFile:
+		return newFileStore()
+	case BackendKeychain:
+		return keychainStore{}, nil
+	case BackendSecretService:
+		return secretServiceStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret backend: %s", backend)
+	}
+}
+
+// Ref builds a reference to key in backend
+func Ref(backend, key string) string {
+	return backend + ":" + key
+}
+
+// ParseRef splits a reference into its backend and key
+func ParseRef(ref string) (backend, key string, err error) {
+	backend, key, ok := strings.Cut(ref, ":")
+	if !ok || backend == "" || key == "" {
+		return "", "", fmt.Errorf("invalid secret reference: %q (expected <backend>:<key>)", ref)
+	}
+	return backend, key, nil
+}
+
+// Resolve looks up the secret a reference points to
+func Resolve(ref string) (string, error) {
+	backend, key, err := ParseRef(ref)
+	if err != nil {
+		return "", err
+	}
+	store, err := Open(backend)
+	if err != nil {
+		return "", err
+	}
+	value, err := store.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", ref, err)
+	}
+	return value, nil
+}
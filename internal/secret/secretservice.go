@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretServiceStore keeps secrets in the freedesktop Secret Service
+// (GNOME Keyring, KWallet) via the secret-tool utility
+type secretServiceStore struct{}
+
+func (secretServiceStore) Get(key string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", serviceName, "account", key).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secret service unavailable (is secret-tool installed?): %w", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (secretServiceStore) Set(key, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", serviceName+" "+key, "service", serviceName, "account", key)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write secret: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (secretServiceStore) Delete(key string) error {
+	err := exec.Command("secret-tool", "clear", "service", serviceName, "account", key).Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("secret service unavailable (is secret-tool installed?): %w", err)
+	}
+	return nil
+}
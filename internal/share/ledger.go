@@ -0,0 +1,111 @@
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/orionnectar/go-azbutils/internal/atomicfile"
+	"github.com/orionnectar/go-azbutils/internal/config"
+)
+
+// Entry records a shared link by the blob and stored access policy backing
+// it. The link itself is a bearer credential and is not kept.
+type Entry struct {
+	ID        string     `json:"id"`
+	Account   string     `json:"account"`
+	Container string     `json:"container"`
+	Blob      string     `json:"blob"`
+	Policy    string     `json:"policy"`
+	Created   time.Time  `json:"created"`
+	Expiry    time.Time  `json:"expiry"`
+	Revoked   *time.Time `json:"revoked,omitempty"`
+}
+
+// Status describes whether the link still works
+func (e *Entry) Status(now time.Time) string {
+	switch {
+	case e.Revoked != nil:
+		return "revoked"
+	case now.After(e.Expiry):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+type Ledger struct {
+	Shares []*Entry `json:"shares"`
+}
+
+// Find returns the entry with the given ID, or nil
+func (l *Ledger) Find(id string) *Entry {
+	for _, e := range l.Shares {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func LedgerPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "shares.json"), nil
+}
+
+// Load reads the ledger, returning an empty one if none has been written yet
+func Load() (*Ledger, error) {
+	path, err := LedgerPath()
+	if err != nil {
+		return nil, err
+	}
+	return load(path)
+}
+
+// Update loads the ledger, applies fn and saves the result while holding the
+// ledger lock. Nothing is written if fn returns an error.
+func Update(fn func(l *Ledger) error) error {
+	path, err := LedgerPath()
+	if err != nil {
+		return err
+	}
+	return config.WithLock(path, func() error {
+		l, err := load(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(l, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize share ledger: %w", err)
+		}
+		return atomicfile.WriteFile(path, 0600, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+	})
+}
+
+func load(path string) (*Ledger, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Ledger{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l Ledger
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid share ledger: %w", err)
+	}
+	return &l, nil
+}
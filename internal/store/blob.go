@@ -0,0 +1,382 @@
+package store
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// copyPollInterval is how often Copy checks a pending server-side copy
+const copyPollInterval = 500 * time.Millisecond
+
+// BlobStore is a Store backed by an Azure Blob Storage account
+type BlobStore struct {
+	client *azblob.Client
+}
+
+// NewBlobStore wraps an azblob client
+func NewBlobStore(client *azblob.Client) *BlobStore {
+	return &BlobStore{client: client}
+}
+
+func (s *BlobStore) blobClient(containerName, name, versionID string) (*blob.Client, error) {
+	client := s.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(name)
+	if versionID == "" {
+		return client, nil
+	}
+	client, err := client.WithVersionID(versionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version ID: %w", err)
+	}
+	return client, nil
+}
+
+func (s *BlobStore) List(ctx context.Context, containerName string, opts ListOptions, fn func(Object) error) error {
+	containerClient := s.client.ServiceClient().NewContainerClient(containerName)
+	include := container.ListBlobsInclude{Versions: opts.Versions, Deleted: opts.Deleted}
+
+	if opts.Delimiter == "" {
+		pager := containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &opts.Prefix, Include: include})
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return translate(err)
+			}
+			for _, item := range page.Segment.BlobItems {
+				if err := fn(blobItemObject(item)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	pager := containerClient.NewListBlobsHierarchyPager(opts.Delimiter, &container.ListBlobsHierarchyOptions{Prefix: &opts.Prefix, Include: include})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return translate(err)
+		}
+		for _, prefix := range page.Segment.BlobPrefixes {
+			if err := fn(Object{Name: *prefix.Name, IsPrefix: true}); err != nil {
+				return err
+			}
+		}
+		for _, item := range page.Segment.BlobItems {
+			if err := fn(blobItemObject(item)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BlobStore) Get(ctx context.Context, containerName, name string, opts *GetOptions) (io.ReadCloser, *Object, error) {
+	client, err := s.blobClient(containerName, name, versionOf(opts))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, nil, translate(err)
+	}
+
+	// A full-blob download returns the stored Content-MD5, if any
+	obj := &Object{Name: name, ContentMD5: resp.ContentMD5}
+	if resp.ContentLength != nil {
+		obj.Size = *resp.ContentLength
+	}
+	if resp.ContentType != nil {
+		obj.ContentType = *resp.ContentType
+	}
+	if resp.ETag != nil {
+		obj.ETag = string(*resp.ETag)
+	}
+	if resp.LastModified != nil {
+		obj.LastModified = *resp.LastModified
+	}
+	if resp.VersionID != nil {
+		obj.VersionID = *resp.VersionID
+	}
+	if resp.IsCurrentVersion != nil {
+		obj.IsCurrentVersion = *resp.IsCurrentVersion
+	}
+	return resp.NewRetryReader(ctx, nil), obj, nil
+}
+
+func (s *BlobStore) Put(ctx context.Context, containerName, name string, r io.Reader, opts *PutOptions) error {
+	if opts == nil {
+		opts = &PutOptions{}
+	}
+	// Staged blocks carry a CRC64 the service checks on receipt; a blob that
+	// fits one block is sent with a single Put Blob, which the SDK sends
+	// without one. The Content-MD5 is stored, not checked, for downloads to
+	// check against. UploadStream reads r to the end before it sends the
+	// headers, so it is computed from the bytes actually uploaded.
+	headers := &blob.HTTPHeaders{BlobContentMD5: opts.ContentMD5}
+	if opts.ContentMD5 == nil {
+		r = &md5Reader{r: r, hash: md5.New(), headers: headers}
+	}
+	uploadOpts := &azblob.UploadStreamOptions{
+		AccessConditions:        writeConditions(opts),
+		TransactionalValidation: blob.TransferValidationTypeComputeCRC64(),
+		HTTPHeaders:             headers,
+	}
+	if opts.ContentType != "" {
+		uploadOpts.HTTPHeaders.BlobContentType = &opts.ContentType
+	}
+	_, err := s.client.UploadStream(ctx, containerName, name, r, uploadOpts)
+	return translate(err)
+}
+
+// md5Reader hashes the data read through it and sets the Content-MD5 of
+// headers once it reaches the end
+type md5Reader struct {
+	r       io.Reader
+	hash    hash.Hash
+	headers *blob.HTTPHeaders
+}
+
+func (m *md5Reader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.hash.Write(p[:n])
+	if err == io.EOF {
+		m.headers.BlobContentMD5 = m.hash.Sum(nil)
+	}
+	return n, err
+}
+
+// Update sets the HTTP headers and metadata in separate calls, each of which
+// replaces the whole set, so the current values are read and merged first
+func (s *BlobStore) Update(ctx context.Context, containerName, name string, opts UpdateOptions) error {
+	client, _ := s.blobClient(containerName, name, "")
+	conditions := func(etag *azcore.ETag) *blob.AccessConditions {
+		ac := leaseConditions(opts.LeaseID)
+		if etag == nil {
+			return ac
+		}
+		if ac == nil {
+			ac = &blob.AccessConditions{}
+		}
+		ac.ModifiedAccessConditions = &blob.ModifiedAccessConditions{IfMatch: etag}
+		return ac
+	}
+
+	var etag *azcore.ETag
+	if opts.IfMatch != "" {
+		etag = to.Ptr(azcore.ETag(opts.IfMatch))
+	}
+	props, err := client.GetProperties(ctx, &blob.GetPropertiesOptions{AccessConditions: conditions(etag)})
+	if err != nil {
+		return translate(err)
+	}
+	// Pin the later calls to this state of the blob
+	etag = props.ETag
+
+	if opts.ContentMD5 != nil {
+		headers := blob.ParseHTTPHeaders(props)
+		headers.BlobContentMD5 = opts.ContentMD5
+		resp, err := client.SetHTTPHeaders(ctx, headers, &blob.SetHTTPHeadersOptions{AccessConditions: conditions(etag)})
+		if err != nil {
+			return translate(err)
+		}
+		etag = resp.ETag
+	}
+	if len(opts.Metadata) > 0 {
+		metadata := props.Metadata
+		if metadata == nil {
+			metadata = make(map[string]*string)
+		}
+		for k, v := range opts.Metadata {
+			metadata[k] = to.Ptr(v)
+		}
+		_, err := client.SetMetadata(ctx, metadata, &blob.SetMetadataOptions{AccessConditions: conditions(etag)})
+		if err != nil {
+			return translate(err)
+		}
+	}
+	return nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, containerName, name string, opts *DeleteOptions) error {
+	if opts == nil {
+		opts = &DeleteOptions{}
+	}
+	client, _ := s.blobClient(containerName, name, "")
+	_, err := client.Delete(ctx, &blob.DeleteOptions{
+		DeleteSnapshots:  to.Ptr(blob.DeleteSnapshotsOptionTypeInclude),
+		AccessConditions: leaseConditions(opts.LeaseID),
+	})
+	return translate(err)
+}
+
+// Copy starts a server-side copy and waits for it to finish
+func (s *BlobStore) Copy(ctx context.Context, srcContainer, srcName, dstContainer, dstName string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	src, err := s.blobClient(srcContainer, srcName, opts.SourceVersionID)
+	if err != nil {
+		return err
+	}
+	dst, _ := s.blobClient(dstContainer, dstName, "")
+
+	resp, err := dst.StartCopyFromURL(ctx, src.URL(), &blob.StartCopyFromURLOptions{
+		AccessConditions: leaseConditions(opts.LeaseID),
+	})
+	if err != nil {
+		return translate(err)
+	}
+
+	status := resp.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+		props, err := dst.GetProperties(ctx, nil)
+		if err != nil {
+			return translate(err)
+		}
+		status = props.CopyStatus
+		if status != nil && *status != blob.CopyStatusTypePending && *status != blob.CopyStatusTypeSuccess {
+			desc := ""
+			if props.CopyStatusDescription != nil {
+				desc = *props.CopyStatusDescription
+			}
+			return fmt.Errorf("copy %s: %s", *status, desc)
+		}
+	}
+	return nil
+}
+
+func (s *BlobStore) Properties(ctx context.Context, containerName, name string, opts *GetOptions) (*Object, error) {
+	client, err := s.blobClient(containerName, name, versionOf(opts))
+	if err != nil {
+		return nil, err
+	}
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	obj := &Object{Name: name, ContentMD5: props.ContentMD5}
+	if props.ContentLength != nil {
+		obj.Size = *props.ContentLength
+	}
+	if props.ContentType != nil {
+		obj.ContentType = *props.ContentType
+	}
+	if props.ETag != nil {
+		obj.ETag = string(*props.ETag)
+	}
+	if props.LastModified != nil {
+		obj.LastModified = *props.LastModified
+	}
+	if props.VersionID != nil {
+		obj.VersionID = *props.VersionID
+	}
+	if props.IsCurrentVersion != nil {
+		obj.IsCurrentVersion = *props.IsCurrentVersion
+	}
+	return obj, nil
+}
+
+func blobItemObject(item *container.BlobItem) Object {
+	obj := Object{Name: *item.Name}
+	if item.VersionID != nil {
+		obj.VersionID = *item.VersionID
+	}
+	if item.IsCurrentVersion != nil {
+		obj.IsCurrentVersion = *item.IsCurrentVersion
+	}
+	if item.Deleted != nil {
+		obj.Deleted = *item.Deleted
+	}
+	if props := item.Properties; props != nil {
+		if props.ContentLength != nil {
+			obj.Size = *props.ContentLength
+		}
+		if props.ContentType != nil {
+			obj.ContentType = *props.ContentType
+		}
+		if props.ETag != nil {
+			obj.ETag = string(*props.ETag)
+		}
+		if props.LastModified != nil {
+			obj.LastModified = *props.LastModified
+		}
+		if props.RemainingRetentionDays != nil {
+			obj.RemainingRetentionDays = *props.RemainingRetentionDays
+		}
+		obj.ContentMD5 = props.ContentMD5
+	}
+	return obj
+}
+
+func leaseConditions(leaseID string) *blob.AccessConditions {
+	if leaseID == "" {
+		return nil
+	}
+	return &blob.AccessConditions{LeaseAccessConditions: &blob.LeaseAccessConditions{LeaseID: &leaseID}}
+}
+
+// writeConditions returns the lease and preconditions of a Put
+func writeConditions(opts *PutOptions) *blob.AccessConditions {
+	ac := leaseConditions(opts.LeaseID)
+	if opts.IfMatch == "" && opts.IfNoneMatch == "" && opts.IfUnmodifiedSince.IsZero() {
+		return ac
+	}
+	if ac == nil {
+		ac = &blob.AccessConditions{}
+	}
+	ac.ModifiedAccessConditions = &blob.ModifiedAccessConditions{}
+	if opts.IfMatch != "" {
+		ac.ModifiedAccessConditions.IfMatch = to.Ptr(azcore.ETag(opts.IfMatch))
+	}
+	if opts.IfNoneMatch != "" {
+		ac.ModifiedAccessConditions.IfNoneMatch = to.Ptr(azcore.ETag(opts.IfNoneMatch))
+	}
+	if !opts.IfUnmodifiedSince.IsZero() {
+		ac.ModifiedAccessConditions.IfUnmodifiedSince = &opts.IfUnmodifiedSince
+	}
+	return ac
+}
+
+func versionOf(opts *GetOptions) string {
+	if opts == nil {
+		return ""
+	}
+	return opts.VersionID
+}
+
+// translate wraps missing-container and missing-blob errors with ErrNotFound,
+// existing blobs with ErrExists and failed preconditions with
+// ErrConditionNotMet
+func translate(err error) error {
+	if err != nil && bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound, bloberror.ResourceNotFound) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if err != nil && bloberror.HasCode(err, bloberror.BlobAlreadyExists) {
+		return fmt.Errorf("%w: %w", ErrExists, err)
+	}
+	// A HEAD request fails its precondition with a bare 412, without a code
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %w", ErrConditionNotMet, err)
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStore is a Store over a local directory: each top-level directory is a
+// container and blob names map to relative file paths. Versions, soft delete
+// and leases are not modelled.
+type FileStore struct {
+	root string
+}
+
+// NewFileStore returns a store rooted at dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{root: dir}
+}
+
+// containerDir maps a container to its directory inside the root
+func (s *FileStore) containerDir(containerName string) (string, error) {
+	if containerName == "" || containerName == "." || containerName == ".." || strings.ContainsAny(containerName, `/\`) {
+		return "", fmt.Errorf("invalid container name '%s'", containerName)
+	}
+	return filepath.Join(s.root, containerName), nil
+}
+
+// path maps a container and blob name to a file path inside the root
+func (s *FileStore) path(containerName, name string) (string, error) {
+	dir, err := s.containerDir(containerName)
+	if err != nil {
+		return "", err
+	}
+	clean := path.Clean("/" + name)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid blob name '%s'", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+func (s *FileStore) open(containerName, name string, opts *GetOptions) (string, fs.FileInfo, error) {
+	file, err := s.path(containerName, name)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) || versionOf(opts) != "" {
+		return "", nil, fmt.Errorf("%w: blob '%s'", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return file, info, nil
+}
+
+func (s *FileStore) List(ctx context.Context, containerName string, opts ListOptions, fn func(Object) error) error {
+	dir, err := s.containerDir(containerName)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: container '%s'", ErrNotFound, containerName)
+	}
+
+	var objects []Object
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObject(filepath.ToSlash(rel), info))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return listObjects(objects, opts, fn)
+}
+
+// Get returns no ContentMD5: files carry no stored hash
+func (s *FileStore) Get(ctx context.Context, containerName, name string, opts *GetOptions) (io.ReadCloser, *Object, error) {
+	file, info, err := s.open(containerName, name, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := fileObject(name, info)
+	return f, &obj, nil
+}
+
+// Put writes to a temporary file and renames it, so readers never see a
+// partial blob. Preconditions are checked before the write, not atomically.
+func (s *FileStore) Put(ctx context.Context, containerName, name string, r io.Reader, opts *PutOptions) error {
+	file, err := s.path(containerName, name)
+	if err != nil {
+		return err
+	}
+	if opts != nil {
+		var current *Object
+		if _, info, err := s.open(containerName, name, nil); err == nil {
+			obj := fileObject(name, info)
+			current = &obj
+		}
+		if err := checkWrite(current, name, opts); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".azbutils-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// Delete removes the file and any directories it leaves empty
+func (s *FileStore) Delete(ctx context.Context, containerName, name string, opts *DeleteOptions) error {
+	file, _, err := s.open(containerName, name, nil)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+
+	containerDir, _ := s.containerDir(containerName)
+	for dir := filepath.Dir(file); dir != containerDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Update fails: files carry no blob properties or metadata
+func (s *FileStore) Update(ctx context.Context, containerName, name string, opts UpdateOptions) error {
+	if _, _, err := s.open(containerName, name, nil); err != nil {
+		return err
+	}
+	return fmt.Errorf("blob properties: %w", ErrNotSupported)
+}
+
+func (s *FileStore) Copy(ctx context.Context, srcContainer, srcName, dstContainer, dstName string, opts *CopyOptions) error {
+	getOpts := &GetOptions{}
+	if opts != nil {
+		getOpts.VersionID = opts.SourceVersionID
+	}
+	r, _, err := s.Get(ctx, srcContainer, srcName, getOpts)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.Put(ctx, dstContainer, dstName, r, nil)
+}
+
+func (s *FileStore) Properties(ctx context.Context, containerName, name string, opts *GetOptions) (*Object, error) {
+	_, info, err := s.open(containerName, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	obj := fileObject(name, info)
+	return &obj, nil
+}
+
+func fileObject(name string, info fs.FileInfo) Object {
+	return Object{
+		Name:             name,
+		Size:             info.Size(),
+		ContentType:      mime.TypeByExtension(path.Ext(name)),
+		ETag:             fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size()),
+		LastModified:     info.ModTime().UTC(),
+		IsCurrentVersion: true,
+	}
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps blobs in memory. Containers are created
+// on first write; versions, soft delete and leases are not modelled.
+type MemoryStore struct {
+	mu         sync.Mutex
+	containers map[string]map[string]*memoryBlob
+	etag       int
+}
+
+type memoryBlob struct {
+	obj      Object
+	data     []byte
+	metadata map[string]string
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{containers: make(map[string]map[string]*memoryBlob)}
+}
+
+func (s *MemoryStore) lookup(containerName, name string, opts *GetOptions) (*memoryBlob, error) {
+	blobs, ok := s.containers[containerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: container '%s'", ErrNotFound, containerName)
+	}
+	b, ok := blobs[name]
+	if !ok || versionOf(opts) != "" {
+		return nil, fmt.Errorf("%w: blob '%s'", ErrNotFound, name)
+	}
+	return b, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, containerName string, opts ListOptions, fn func(Object) error) error {
+	s.mu.Lock()
+	blobs, ok := s.containers[containerName]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: container '%s'", ErrNotFound, containerName)
+	}
+	objects := make([]Object, 0, len(blobs))
+	for _, b := range blobs {
+		objects = append(objects, b.obj)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return listObjects(objects, opts, fn)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, containerName, name string, opts *GetOptions) (io.ReadCloser, *Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.lookup(containerName, name, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := b.obj
+	return io.NopCloser(bytes.NewReader(b.data)), &obj, nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, containerName, name string, r io.Reader, opts *PutOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &PutOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var current *Object
+	if b, err := s.lookup(containerName, name, nil); err == nil {
+		current = &b.obj
+	}
+	if err := checkWrite(current, name, opts); err != nil {
+		return err
+	}
+	s.put(containerName, name, data, opts.ContentType, opts.ContentMD5)
+	return nil
+}
+
+// put stores data under name; a nil contentMD5 is computed from data
+func (s *MemoryStore) put(containerName, name string, data []byte, contentType string, contentMD5 []byte) {
+	if contentMD5 == nil {
+		sum := md5.Sum(data)
+		contentMD5 = sum[:]
+	}
+	blobs, ok := s.containers[containerName]
+	if !ok {
+		blobs = make(map[string]*memoryBlob)
+		s.containers[containerName] = blobs
+	}
+	s.etag++
+	blobs[name] = &memoryBlob{
+		data: data,
+		obj: Object{
+			Name:             name,
+			Size:             int64(len(data)),
+			ContentType:      contentType,
+			ContentMD5:       contentMD5,
+			ETag:             fmt.Sprintf("\"%d\"", s.etag),
+			LastModified:     time.Now().UTC(),
+			IsCurrentVersion: true,
+		},
+	}
+}
+
+func (s *MemoryStore) Update(ctx context.Context, containerName, name string, opts UpdateOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.lookup(containerName, name, nil)
+	if err != nil {
+		return err
+	}
+	if opts.IfMatch != "" && opts.IfMatch != b.obj.ETag {
+		return fmt.Errorf("%w: blob '%s' has changed", ErrConditionNotMet, name)
+	}
+	if opts.ContentMD5 != nil {
+		b.obj.ContentMD5 = opts.ContentMD5
+	}
+	if len(opts.Metadata) > 0 {
+		if b.metadata == nil {
+			b.metadata = make(map[string]string)
+		}
+		maps.Copy(b.metadata, opts.Metadata)
+	}
+	s.etag++
+	b.obj.ETag = fmt.Sprintf("\"%d\"", s.etag)
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, containerName, name string, opts *DeleteOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookup(containerName, name, nil); err != nil {
+		return err
+	}
+	delete(s.containers[containerName], name)
+	return nil
+}
+
+func (s *MemoryStore) Copy(ctx context.Context, srcContainer, srcName, dstContainer, dstName string, opts *CopyOptions) error {
+	getOpts := &GetOptions{}
+	if opts != nil {
+		getOpts.VersionID = opts.SourceVersionID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	src, err := s.lookup(srcContainer, srcName, getOpts)
+	if err != nil {
+		return err
+	}
+	s.put(dstContainer, dstName, bytes.Clone(src.data), src.obj.ContentType, src.obj.ContentMD5)
+	return nil
+}
+
+func (s *MemoryStore) Properties(ctx context.Context, containerName, name string, opts *GetOptions) (*Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.lookup(containerName, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	obj := b.obj
+	return &obj, nil
+}
@@ -0,0 +1,159 @@
+// Package store abstracts blob storage behind a small interface, so command
+// logic can run against Azure, memory or a local directory.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned (wrapped) when a container or blob does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrConditionNotMet is returned (wrapped) when a blob changed since its ETag
+// was read, or otherwise fails a write's preconditions
+var ErrConditionNotMet = errors.New("condition not met")
+
+// ErrExists is returned (wrapped) when a write that must not overwrite finds
+// the blob already there
+var ErrExists = errors.New("already exists")
+
+// ErrNotSupported is returned by stores that cannot model an operation
+var ErrNotSupported = errors.New("not supported by this store")
+
+// Object describes a blob, or a virtual directory in a delimited listing
+type Object struct {
+	Name         string
+	Size         int64
+	ContentType  string
+	ContentMD5   []byte
+	ETag         string
+	LastModified time.Time
+	// IsPrefix marks a virtual directory returned by a delimited listing
+	IsPrefix bool
+	// Version and soft-delete details, set when listed with Versions / Deleted
+	VersionID              string
+	IsCurrentVersion       bool
+	Deleted                bool
+	RemainingRetentionDays int32
+}
+
+// ListOptions selects the blobs returned by List
+type ListOptions struct {
+	Prefix string
+	// Delimiter groups names into virtual directories; empty lists flat
+	Delimiter string
+	Versions  bool
+	Deleted   bool
+}
+
+// GetOptions selects the blob read by Get and Properties
+type GetOptions struct {
+	VersionID string
+}
+
+// PutOptions configures a write
+type PutOptions struct {
+	ContentType string
+	// ContentMD5 is stored as the blob's Content-MD5; stores that model
+	// hashes compute it when it is not given
+	ContentMD5 []byte
+	// LeaseID is required to overwrite a leased blob
+	LeaseID string
+	// IfMatch only overwrites the blob with this ETag; IfNoneMatch "*"
+	// fails with ErrExists if the blob exists. Other failed conditions
+	// return ErrConditionNotMet.
+	IfMatch     string
+	IfNoneMatch string
+	// IfUnmodifiedSince only overwrites a blob not modified after it
+	IfUnmodifiedSince time.Time
+}
+
+// checkWrite applies the preconditions of opts to the current blob, nil if
+// there is none, for stores that evaluate them themselves
+func checkWrite(current *Object, name string, opts *PutOptions) error {
+	switch {
+	case current != nil && opts.IfNoneMatch == "*":
+		return fmt.Errorf("%w: blob '%s'", ErrExists, name)
+	case opts.IfMatch != "" && (current == nil || current.ETag != opts.IfMatch):
+		return fmt.Errorf("%w: blob '%s' does not have ETag %s", ErrConditionNotMet, name, opts.IfMatch)
+	case !opts.IfUnmodifiedSince.IsZero() && current != nil && current.LastModified.After(opts.IfUnmodifiedSince):
+		return fmt.Errorf("%w: blob '%s' was modified since %s", ErrConditionNotMet, name, opts.IfUnmodifiedSince.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// UpdateOptions selects the properties changed by Update; unset fields keep
+// their value
+type UpdateOptions struct {
+	ContentMD5 []byte
+	// Metadata entries are added to the blob's metadata, replacing entries
+	// of the same name
+	Metadata map[string]string
+	// IfMatch makes the update fail with ErrConditionNotMet unless the
+	// blob's ETag still matches
+	IfMatch string
+	LeaseID string
+}
+
+// DeleteOptions configures a delete
+type DeleteOptions struct {
+	LeaseID string
+}
+
+// CopyOptions configures a copy within a store
+type CopyOptions struct {
+	SourceVersionID string
+	// LeaseID is required to overwrite a leased destination blob
+	LeaseID string
+}
+
+// Store reads and writes blobs in named containers
+type Store interface {
+	// List calls fn for each matching object; virtual directories come first
+	List(ctx context.Context, container string, opts ListOptions, fn func(Object) error) error
+	// Get returns the blob's contents along with its properties, read from
+	// the same response so that they match the contents
+	Get(ctx context.Context, container, name string, opts *GetOptions) (io.ReadCloser, *Object, error)
+	Put(ctx context.Context, container, name string, r io.Reader, opts *PutOptions) error
+	// Update changes properties of an existing blob without rewriting it
+	Update(ctx context.Context, container, name string, opts UpdateOptions) error
+	Delete(ctx context.Context, container, name string, opts *DeleteOptions) error
+	Copy(ctx context.Context, srcContainer, srcName, dstContainer, dstName string, opts *CopyOptions) error
+	Properties(ctx context.Context, container, name string, opts *GetOptions) (*Object, error)
+}
+
+// listObjects applies the prefix and delimiter of opts to objects sorted by
+// name, the way the Blob service does for a listing
+func listObjects(objects []Object, opts ListOptions, fn func(Object) error) error {
+	var prefixes, items []Object
+	seen := make(map[string]bool)
+	for _, obj := range objects {
+		rest, ok := strings.CutPrefix(obj.Name, opts.Prefix)
+		if !ok {
+			continue
+		}
+		if opts.Delimiter != "" {
+			if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+				dir := opts.Prefix + rest[:i+len(opts.Delimiter)]
+				if !seen[dir] {
+					seen[dir] = true
+					prefixes = append(prefixes, Object{Name: dir, IsPrefix: true})
+				}
+				continue
+			}
+		}
+		items = append(items, obj)
+	}
+
+	for _, obj := range append(prefixes, items...) {
+		if err := fn(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
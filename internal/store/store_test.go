@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testStores returns a fresh instance of each offline store
+func testStores(t *testing.T) map[string]Store {
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   NewFileStore(t.TempDir()),
+	}
+}
+
+func put(t *testing.T, s Store, container, name, data string) {
+	t.Helper()
+	if err := s.Put(context.Background(), container, name, strings.NewReader(data), nil); err != nil {
+		t.Fatalf("Put %s: %v", name, err)
+	}
+}
+
+func read(t *testing.T, s Store, container, name string) string {
+	t.Helper()
+	r, _, err := s.Get(context.Background(), container, name, nil)
+	if err != nil {
+		t.Fatalf("Get %s: %v", name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestList(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, blob := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "dir2/d.txt"} {
+				put(t, s, "data", blob, blob)
+			}
+
+			list := func(opts ListOptions) []string {
+				var names []string
+				err := s.List(context.Background(), "data", opts, func(obj Object) error {
+					names = append(names, obj.Name)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				return names
+			}
+			if got, want := list(ListOptions{Delimiter: "/"}), []string{"dir/", "dir2/", "a.txt"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("delimited = %q, want %q", got, want)
+			}
+			if got, want := list(ListOptions{Prefix: "dir/"}), []string{"dir/b.txt", "dir/sub/c.txt"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("prefix = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPutConditions(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			put(t, s, "data", "f.txt", "v1")
+			obj, err := s.Properties(ctx, "data", "f.txt", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Put(ctx, "data", "f.txt", strings.NewReader("v2"), &PutOptions{IfNoneMatch: "*"})
+			if !errors.Is(err, ErrExists) {
+				t.Errorf("IfNoneMatch: err = %v, want ErrExists", err)
+			}
+			err = s.Put(ctx, "data", "f.txt", strings.NewReader("v2"), &PutOptions{IfMatch: "stale"})
+			if !errors.Is(err, ErrConditionNotMet) {
+				t.Errorf("stale IfMatch: err = %v, want ErrConditionNotMet", err)
+			}
+			if err := s.Put(ctx, "data", "f.txt", strings.NewReader("v2"), &PutOptions{IfMatch: obj.ETag}); err != nil {
+				t.Errorf("IfMatch: %v", err)
+			}
+			if got := read(t, s, "data", "f.txt"); got != "v2" {
+				t.Errorf("contents = %q, want v2", got)
+			}
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			put(t, s, "src", "f.txt", "data")
+			if err := s.Copy(ctx, "src", "f.txt", "dst", "g.txt", nil); err != nil {
+				t.Fatalf("Copy: %v", err)
+			}
+			if got := read(t, s, "dst", "g.txt"); got != "data" {
+				t.Errorf("copy = %q, want data", got)
+			}
+			if err := s.Copy(ctx, "src", "missing.txt", "dst", "h.txt", nil); !errors.Is(err, ErrNotFound) {
+				t.Errorf("missing source: err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			put(t, s, "data", "f.txt", "x")
+			if err := s.Delete(context.Background(), "data", "f.txt", nil); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := s.Delete(context.Background(), "data", "f.txt", nil); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Package azbutils exposes the operations of the azbutils CLI as a Go library.
+//
+// Paths are given the same way as on the command line: local file paths,
+// az://account//container/path, https:// blob URLs, short container/path
+// forms resolved against the default account, and @alias paths. Accounts
+// and aliases come from the azbutils config file.
+//
+//	res, err := azbutils.Copy(ctx, "./data", "az://myaccount//backups/data", &azbutils.CopyOptions{Recursive: true})
+//
+// The package-level functions load the config on every call; create a Client
+// to reuse it, or to run against a Store other than Azure.
+package azbutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/azure"
+	"github.com/orionnectar/go-azbutils/internal/config"
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// Store is the blob storage interface the operations run against
+type Store = store.Store
+
+// Object describes a blob, or a virtual directory in a listing
+type Object = store.Object
+
+// ErrNotFound is returned (wrapped) when a container or blob does not exist
+var ErrNotFound = store.ErrNotFound
+
+// ErrExists is returned (wrapped) when an upload with NoClobber finds the
+// blob already there
+var ErrExists = store.ErrExists
+
+// ErrConditionNotMet is returned (wrapped) when a blob changed since its ETag
+// was read, or fails another write condition
+var ErrConditionNotMet = store.ErrConditionNotMet
+
+// ErrInterrupted is returned with a partial result when an Interrupt channel
+// stopped a recursive copy or sync
+var ErrInterrupted = errors.New("interrupted")
+
+// NewMemoryStore returns an empty in-memory Store, e.g. for tests
+func NewMemoryStore() Store {
+	return store.NewMemoryStore()
+}
+
+// NewFileStore returns a Store over a local directory whose subdirectories
+// are containers
+func NewFileStore(dir string) Store {
+	return store.NewFileStore(dir)
+}
+
+// Options configures a Client
+type Options struct {
+	// ConfigPath overrides the config file; by default the CLI's lookup
+	// order applies ($AZBUTILS_CONFIG, then the XDG config directory)
+	ConfigPath string
+	// Account is used for short paths that name no account
+	Account string
+	// Store, when set, serves every account instead of Azure
+	Store Store
+}
+
+// Client runs operations against the accounts of one config
+type Client struct {
+	cfg    *config.Config
+	parser *azpath.Parser
+	store  Store
+}
+
+// New loads the config and returns a Client. opts may be nil. The network
+// section of the config (retries, proxy, CA bundle) applies process-wide.
+func New(opts *Options) (*Client, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	var cfg *config.Config
+	var err error
+	if opts.ConfigPath != "" {
+		cfg, err = config.LoadFrom(opts.ConfigPath)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if opts.Store == nil {
+		if err := azure.Configure(cfg.Network); err != nil {
+			return nil, fmt.Errorf("invalid network settings: %w", err)
+		}
+	}
+
+	return &Client{
+		cfg:    cfg,
+		parser: azure.NewPathParser(cfg, opts.Account),
+		store:  opts.Store,
+	}, nil
+}
+
+// Copy copies with a Client created from the default config
+func Copy(ctx context.Context, src, dst string, opts *CopyOptions) (*CopyResult, error) {
+	c, err := New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Copy(ctx, src, dst, opts)
+}
+
+// List lists with a Client created from the default config
+func List(ctx context.Context, path string, opts *ListOptions) (*ListResult, error) {
+	c, err := New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.List(ctx, path, opts)
+}
+
+// ListFunc streams a listing with a Client created from the default config
+func ListFunc(ctx context.Context, path string, opts *ListOptions, fn func(Entry) error) error {
+	c, err := New(nil)
+	if err != nil {
+		return err
+	}
+	return c.ListFunc(ctx, path, opts, fn)
+}
+
+// Cat writes a blob to w with a Client created from the default config
+func Cat(ctx context.Context, path string, w io.Writer, opts *CatOptions) (int64, error) {
+	c, err := New(nil)
+	if err != nil {
+		return 0, err
+	}
+	return c.Cat(ctx, path, w, opts)
+}
+
+// Sync syncs with a Client created from the default config
+func Sync(ctx context.Context, src, dst string, opts *SyncOptions) (*SyncResult, error) {
+	c, err := New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Sync(ctx, src, dst, opts)
+}
+
+// Verify compares by hash with a Client created from the default config
+func Verify(ctx context.Context, local, remote string, opts *VerifyOptions) (*VerifyResult, error) {
+	c, err := New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Verify(ctx, local, remote, opts)
+}
+
+// Hash hashes blobs with a Client created from the default config
+func Hash(ctx context.Context, path string, opts *HashOptions) (*HashResult, error) {
+	c, err := New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Hash(ctx, path, opts)
+}
+
+// IsRemote reports whether path names blob storage rather than a local file
+func IsRemote(path string) bool {
+	return azpath.IsRemote(path)
+}
+
+// resolve parses a remote path and returns the store of its account
+func (c *Client) resolve(path string) (*azpath.BlobPath, Store, error) {
+	p, err := c.parser.Parse(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.store != nil {
+		return p, c.store, nil
+	}
+
+	acct := c.cfg.Accounts[p.Account]
+	if acct == nil {
+		return nil, nil, fmt.Errorf("no account found in config for '%s'", p.Account)
+	}
+	client, err := azure.ClientFor(p.Account, acct)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+	return p, store.NewBlobStore(client), nil
+}
+
+// resolveDestination is resolve for the destination of an upload, which
+// cannot be a blob version: versions are read-only
+func (c *Client) resolveDestination(path string) (*azpath.BlobPath, Store, error) {
+	p, st, err := c.resolve(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid destination path: %w", err)
+	}
+	if p.VersionID != "" {
+		return nil, nil, fmt.Errorf("cannot upload to '%s': it names blob version %s, and versions are read-only", path, p.VersionID)
+	}
+	return p, st, nil
+}
+
+// versionID merges an explicit version with a version pinned in the path
+func versionID(p *azpath.BlobPath, explicit string) (string, error) {
+	if explicit != "" && p.VersionID != "" && explicit != p.VersionID {
+		return "", fmt.Errorf("conflicting version IDs: '%s' in path and '%s' requested", p.VersionID, explicit)
+	}
+	if explicit != "" {
+		return explicit, nil
+	}
+	return p.VersionID, nil
+}
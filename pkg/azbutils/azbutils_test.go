@@ -0,0 +1,324 @@
+package azbutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// newTestClient returns a Client over st with an empty config
+func newTestClient(t *testing.T, st Store) *Client {
+	t.Helper()
+	c, err := New(&Options{ConfigPath: filepath.Join(t.TempDir(), "config.json"), Store: st})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+// writeFiles creates files under dir, keyed by slash-separated relative path
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func putBlob(t *testing.T, st Store, container, name, data string) {
+	t.Helper()
+	if err := st.Put(context.Background(), container, name, strings.NewReader(data), nil); err != nil {
+		t.Fatalf("Put %s: %v", name, err)
+	}
+}
+
+func entryNames(res *ListResult) []string {
+	var names []string
+	for _, e := range res.Entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"file":   func(t *testing.T) Store { return NewFileStore(t.TempDir()) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			st := newStore(t)
+			c := newTestClient(t, st)
+
+			src := t.TempDir()
+			files := map[string]string{"a.txt": "alpha", "sub/b.txt": "bravo", "sub/deep/c.txt": "charlie"}
+			writeFiles(t, src, files)
+
+			res, err := c.Copy(ctx, src, "az://acct//data/backup", &CopyOptions{Recursive: true})
+			if err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+			if len(res.Transfers) != 3 || res.Bytes != int64(len("alpha")+len("bravo")+len("charlie")) {
+				t.Fatalf("upload result = %d transfers, %d bytes", len(res.Transfers), res.Bytes)
+			}
+
+			list, err := c.List(ctx, "az://acct//data/backup/", nil)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got, want := entryNames(list), []string{"backup/sub/", "backup/a.txt"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("List = %q, want %q", got, want)
+			}
+			list, err = c.List(ctx, "az://acct//data/backup/", &ListOptions{Recursive: true})
+			if err != nil {
+				t.Fatalf("List -r: %v", err)
+			}
+			if got, want := entryNames(list), []string{"backup/a.txt", "backup/sub/b.txt", "backup/sub/deep/c.txt"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("List -r = %q, want %q", got, want)
+			}
+			if got, want := list.Entries[0].URL, "az://acct//data/backup/a.txt"; got != want {
+				t.Errorf("URL = %q, want %q", got, want)
+			}
+
+			var buf bytes.Buffer
+			if _, err := c.Cat(ctx, "az://acct//data/backup/sub/b.txt", &buf, nil); err != nil {
+				t.Fatalf("Cat: %v", err)
+			}
+			if buf.String() != "bravo" {
+				t.Errorf("Cat = %q, want %q", buf.String(), "bravo")
+			}
+
+			dst := t.TempDir()
+			if _, err := c.Copy(ctx, "az://acct//data/backup", dst, &CopyOptions{Recursive: true}); err != nil {
+				t.Fatalf("download: %v", err)
+			}
+			for rel, want := range files {
+				got, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(rel)))
+				if err != nil || string(got) != want {
+					t.Errorf("downloaded %s = %q, %v; want %q", rel, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyUploadStoresMD5(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+
+	src := filepath.Join(t.TempDir(), "f.txt")
+	writeFiles(t, filepath.Dir(src), map[string]string{"f.txt": "hello"})
+	if _, err := c.Copy(ctx, src, "az://acct//data/f.txt", nil); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	obj, err := st.Properties(ctx, "data", "f.txt", nil)
+	if err != nil {
+		t.Fatalf("Properties: %v", err)
+	}
+	if want := md5.Sum([]byte("hello")); !bytes.Equal(obj.ContentMD5, want[:]) {
+		t.Errorf("ContentMD5 = %x, want %x", obj.ContentMD5, want)
+	}
+}
+
+func TestCopyRecursiveStaysInDirectory(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "logs/app.log", "in")
+	putBlob(t, st, "data", "logs-archive/old.log", "out")
+
+	dst := t.TempDir()
+	res, err := c.Copy(ctx, "az://acct//data/logs", dst, &CopyOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if len(res.Transfers) != 1 || res.Transfers[0].Source != "az://acct//data/logs/app.log" {
+		t.Errorf("transfers = %+v, want only logs/app.log", res.Transfers)
+	}
+}
+
+func TestCopyOverwriteNever(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "f.txt", "remote")
+
+	src := filepath.Join(t.TempDir(), "f.txt")
+	writeFiles(t, filepath.Dir(src), map[string]string{"f.txt": "local"})
+	var skipped []Transfer
+	res, err := c.Copy(ctx, src, "az://acct//data/f.txt", &CopyOptions{
+		Overwrite: OverwriteNever,
+		OnSkip:    func(t Transfer) { skipped = append(skipped, t) },
+	})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if len(res.Transfers) != 0 || len(res.Skipped) != 1 || len(skipped) != 1 {
+		t.Errorf("result = %+v, skipped %d; want one skip", res, len(skipped))
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.Cat(ctx, "az://acct//data/f.txt", &buf, nil); err != nil || buf.String() != "remote" {
+		t.Errorf("Cat = %q, %v; want the blob unchanged", buf.String(), err)
+	}
+}
+
+func TestCopyNoClobber(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "f.txt", "remote")
+
+	src := filepath.Join(t.TempDir(), "f.txt")
+	writeFiles(t, filepath.Dir(src), map[string]string{"f.txt": "local"})
+	_, err := c.Copy(ctx, src, "az://acct//data/f.txt", &CopyOptions{Conditions: &WriteConditions{NoClobber: true}})
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("err = %v, want ErrExists", err)
+	}
+}
+
+func TestCopyRejectsVersionedDestination(t *testing.T) {
+	c := newTestClient(t, NewMemoryStore())
+	src := filepath.Join(t.TempDir(), "f.txt")
+	writeFiles(t, filepath.Dir(src), map[string]string{"f.txt": "local"})
+
+	_, err := c.Copy(context.Background(), src, "az://acct//data/f.txt#2024-01-01T00:00:00.0000000Z", nil)
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("err = %v, want a read-only version error", err)
+	}
+
+	// Any other '#' is part of the name
+	if _, err := c.Copy(context.Background(), src, "az://acct//data/notes#1.txt", nil); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if _, err := c.store.Properties(context.Background(), "data", "notes#1.txt", nil); err != nil {
+		t.Errorf("blob notes#1.txt: %v", err)
+	}
+}
+
+func TestCatChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	wrong := md5.Sum([]byte("other"))
+	if err := st.Put(ctx, "data", "f.txt", strings.NewReader("hello"), &store.PutOptions{ContentMD5: wrong[:]}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	_, err := c.Cat(ctx, "az://acct//data/f.txt", &buf, nil)
+	var mismatch *ChecksumError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("err = %v, want a *ChecksumError", err)
+	}
+
+	var warned *ChecksumError
+	_, err = c.Cat(ctx, "az://acct//data/f.txt", &buf, &CatOptions{
+		CheckMD5:           ChecksumWarn,
+		OnChecksumMismatch: func(e *ChecksumError) { warned = e },
+	})
+	if err != nil || warned == nil {
+		t.Errorf("warn mode: err = %v, warned = %v", err, warned)
+	}
+}
+
+func TestDownloadChecksumMismatchKeepsNoFile(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	wrong := md5.Sum([]byte("other"))
+	if err := st.Put(ctx, "data", "f.txt", strings.NewReader("hello"), &store.PutOptions{ContentMD5: wrong[:]}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "f.txt")
+	_, err := c.Copy(ctx, "az://acct//data/f.txt", dst, nil)
+	var mismatch *ChecksumError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("err = %v, want a *ChecksumError", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("download left %s behind", dst)
+	}
+}
+
+func TestCatNotFound(t *testing.T) {
+	c := newTestClient(t, NewMemoryStore())
+	_, err := c.Cat(context.Background(), "az://acct//data/missing.txt", &bytes.Buffer{}, nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCopyRecursiveRejectsEscapingNames(t *testing.T) {
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "d/../../escape.txt", "out")
+
+	out := t.TempDir()
+	dst := filepath.Join(out, "a", "b")
+	if _, err := c.Copy(context.Background(), "az://acct//data/d", dst, &CopyOptions{Recursive: true}); err == nil {
+		t.Error("download of an escaping blob name succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(out, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("escape.txt was written outside the destination")
+	}
+}
+
+func TestCopyRecursiveSkipsDirMarkers(t *testing.T) {
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "d/sub/", "")
+	putBlob(t, st, "data", "d/sub/f.txt", "file")
+
+	dst := t.TempDir()
+	res, err := c.Copy(context.Background(), "az://acct//data/d", dst, &CopyOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if len(res.Transfers) != 1 {
+		t.Errorf("transfers = %+v, want only d/sub/f.txt", res.Transfers)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "sub", "f.txt")); err != nil || string(data) != "file" {
+		t.Errorf("sub/f.txt = %q, %v", data, err)
+	}
+}
+
+func TestListFunc(t *testing.T) {
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		putBlob(t, st, "data", name, name)
+	}
+
+	stop := errors.New("stop")
+	var names []string
+	err := c.ListFunc(context.Background(), "az://acct//data", nil, func(e Entry) error {
+		names = append(names, e.Name)
+		if len(names) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want the callback's error", err)
+	}
+	if want := []string{"a.txt", "b.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,52 @@
+package azbutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// CatOptions configures Cat
+type CatOptions struct {
+	// VersionID reads a specific blob version; a version pinned in the path
+	// (#versionId or ?versionid=) works too
+	VersionID string
+	// CheckMD5 selects what happens when the data does not match the blob's
+	// stored MD5. The data is already written to w by then; in
+	// ChecksumFail mode Cat returns a *ChecksumError.
+	CheckMD5 ChecksumMode
+	// OnChecksumMismatch, if set, is called on a mismatch in ChecksumWarn
+	// mode
+	OnChecksumMismatch func(*ChecksumError)
+}
+
+// Cat writes the contents of the blob at path to w and returns the number of
+// bytes written. opts may be nil.
+func (c *Client) Cat(ctx context.Context, path string, w io.Writer, opts *CatOptions) (int64, error) {
+	if opts == nil {
+		opts = &CatOptions{}
+	}
+	p, st, err := c.resolve(path)
+	if err != nil {
+		return 0, err
+	}
+	version, err := versionID(p, opts.VersionID)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, obj, err := st.Get(ctx, p.Container, p.SubPath, &store.GetOptions{VersionID: version})
+	if err != nil {
+		return 0, fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer reader.Close()
+
+	r, check := verifyDownload(reader, obj, p.BuildFullVersion(p.SubPath, version), opts.CheckMD5, opts.OnChecksumMismatch)
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return n, fmt.Errorf("failed to stream blob: %w", err)
+	}
+	return n, check()
+}
@@ -0,0 +1,120 @@
+package azbutils
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// ChecksumMode selects what a download does when the data does not match the
+// blob's stored Content-MD5. Blobs without a stored MD5 are never checked.
+type ChecksumMode int
+
+const (
+	// ChecksumFail discards the download and returns a *ChecksumError
+	ChecksumFail ChecksumMode = iota
+	// ChecksumWarn keeps the download and reports the mismatch to the
+	// options' OnChecksumMismatch
+	ChecksumWarn
+	// ChecksumOff does not hash downloads
+	ChecksumOff
+)
+
+// ParseChecksumMode parses "fail", "warn" or "off"
+func ParseChecksumMode(s string) (ChecksumMode, error) {
+	switch s {
+	case "fail":
+		return ChecksumFail, nil
+	case "warn":
+		return ChecksumWarn, nil
+	case "off":
+		return ChecksumOff, nil
+	}
+	return 0, fmt.Errorf("invalid checksum mode '%s' (want fail, warn or off)", s)
+}
+
+// ChecksumError reports downloaded data that does not match the blob's
+// stored Content-MD5
+type ChecksumError struct {
+	Path     string
+	Expected []byte
+	Actual   []byte
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("MD5 mismatch for %s: stored %s, downloaded %s", e.Path,
+		base64.StdEncoding.EncodeToString(e.Expected), base64.StdEncoding.EncodeToString(e.Actual))
+}
+
+// md5Checker hashes the data read through it and compares the result with
+// the blob's stored MD5
+type md5Checker struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected []byte
+}
+
+// newMD5Checker wraps r; it returns nil when mode is ChecksumOff or the blob
+// has no stored MD5, so there is nothing to check
+func newMD5Checker(r io.Reader, obj *Object, mode ChecksumMode) *md5Checker {
+	if mode == ChecksumOff || obj == nil || len(obj.ContentMD5) == 0 {
+		return nil
+	}
+	h := md5.New()
+	return &md5Checker{r: io.TeeReader(r, h), hash: h, expected: obj.ContentMD5}
+}
+
+func (c *md5Checker) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// check compares the hash of everything read so far with the stored MD5
+func (c *md5Checker) check(path string) *ChecksumError {
+	if actual := c.hash.Sum(nil); !bytes.Equal(actual, c.expected) {
+		return &ChecksumError{Path: path, Expected: c.expected, Actual: actual}
+	}
+	return nil
+}
+
+// verifyDownload wraps r for the check of a download from path and returns
+// the reader to copy from and a function that checks the copied data: it
+// returns the mismatch in ChecksumFail mode and reports it to onMismatch in
+// ChecksumWarn mode
+func verifyDownload(r io.Reader, obj *Object, path string, mode ChecksumMode, onMismatch func(*ChecksumError)) (io.Reader, func() error) {
+	checker := newMD5Checker(r, obj, mode)
+	if checker == nil {
+		return r, func() error { return nil }
+	}
+	return checker, func() error {
+		mismatch := checker.check(path)
+		switch {
+		case mismatch == nil:
+			return nil
+		case mode == ChecksumWarn:
+			if onMismatch != nil {
+				onMismatch(mismatch)
+			}
+			return nil
+		default:
+			return mismatch
+		}
+	}
+}
+
+// fileMD5 returns the MD5 of a local file
+func fileMD5(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,424 @@
+package azbutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/orionnectar/go-azbutils/internal/atomicfile"
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// CopyOptions configures Copy
+type CopyOptions struct {
+	// Recursive copies a directory or every blob under a prefix
+	Recursive bool
+	// DryRun reports the transfers without performing them
+	DryRun bool
+	// VersionID downloads a specific version of a remote source blob
+	VersionID string
+	// LeaseID is required to overwrite a leased destination blob
+	LeaseID string
+	// Overwrite decides whether existing destination files and blobs are
+	// replaced; skipped files are reported to OnSkip
+	Overwrite OverwritePolicy
+	// Conditions guard each uploaded blob against concurrent writers; they
+	// cannot be combined with an Overwrite policy other than OverwriteAlways
+	Conditions *WriteConditions
+	// OnTransfer, if set, is called before each file is copied
+	OnTransfer func(Transfer)
+	// OnSkip, if set, is called for each file the Overwrite policy skips
+	OnSkip func(Transfer)
+	// CheckMD5 selects what a download does when its data does not match
+	// the blob's stored MD5; the default fails the download
+	CheckMD5 ChecksumMode
+	// OnChecksumMismatch, if set, is called for each mismatch in
+	// ChecksumWarn mode
+	OnChecksumMismatch func(*ChecksumError)
+	// Interrupt, when closed, stops a recursive copy once the transfer in
+	// flight completed; Copy then returns the completed transfers along
+	// with ErrInterrupted. Cancel ctx instead to abort the transfer itself.
+	Interrupt <-chan struct{}
+}
+
+// Transfer is one file copied between the local filesystem and blob storage
+type Transfer struct {
+	// Source and Destination are a local path and a full remote path
+	Source      string
+	Destination string
+	// Size is the number of bytes copied; zero in a dry run
+	Size int64
+}
+
+// CopyResult lists the transfers made by Copy
+type CopyResult struct {
+	Transfers []Transfer
+	// Skipped lists the files the Overwrite policy left alone
+	Skipped []Transfer
+	Bytes   int64
+}
+
+func (r *CopyResult) add(t Transfer, skipped bool) {
+	if skipped {
+		r.Skipped = append(r.Skipped, t)
+		return
+	}
+	r.Transfers = append(r.Transfers, t)
+	r.Bytes += t.Size
+}
+
+// OverwritePolicy selects what Copy does with an existing destination
+type OverwritePolicy int
+
+const (
+	// OverwriteAlways replaces the destination
+	OverwriteAlways OverwritePolicy = iota
+	// OverwriteNever skips files whose destination exists
+	OverwriteNever
+	// OverwriteIfSourceNewer replaces the destination only if the source
+	// was modified after it
+	OverwriteIfSourceNewer
+)
+
+// ParseOverwritePolicy parses "always", "never" or "ifSourceNewer"
+func ParseOverwritePolicy(s string) (OverwritePolicy, error) {
+	switch s {
+	case "always":
+		return OverwriteAlways, nil
+	case "never":
+		return OverwriteNever, nil
+	case "ifSourceNewer":
+		return OverwriteIfSourceNewer, nil
+	}
+	return 0, fmt.Errorf("invalid overwrite policy '%s' (want always, never or ifSourceNewer)", s)
+}
+
+// WriteConditions are preconditions on the destination blob of an upload,
+// checked by the service as part of the write. A failed condition returns
+// an error wrapping ErrExists or ErrConditionNotMet.
+type WriteConditions struct {
+	// NoClobber fails if the blob exists (If-None-Match: *)
+	NoClobber bool
+	// IfMatch fails unless the blob has this ETag
+	IfMatch string
+	// IfUnmodifiedSince fails if the blob was modified after this time
+	IfUnmodifiedSince time.Time
+}
+
+// Copy uploads a local file or directory to blob storage, or downloads blobs
+// to the local filesystem. opts may be nil.
+func (c *Client) Copy(ctx context.Context, src, dst string, opts *CopyOptions) (*CopyResult, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	if opts.Conditions != nil && opts.Overwrite != OverwriteAlways {
+		return nil, fmt.Errorf("write conditions cannot be combined with an overwrite policy")
+	}
+
+	if IsRemote(src) {
+		if opts.Conditions != nil {
+			return nil, fmt.Errorf("write conditions only apply to uploads")
+		}
+		if IsRemote(dst) {
+			return nil, fmt.Errorf("copying between two remote paths is not supported")
+		}
+		p, st, err := c.resolve(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source path: %w", err)
+		}
+		if opts.Recursive {
+			if p.VersionID != "" || opts.VersionID != "" {
+				return nil, fmt.Errorf("a version ID cannot be used with a recursive copy")
+			}
+			return downloadDirectory(ctx, st, p, dst, opts)
+		}
+
+		version, err := versionID(p, opts.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		// Downloading into an existing directory keeps the blob's base name
+		if info, err := os.Stat(dst); err == nil && info.IsDir() {
+			dst = filepath.Join(dst, filepath.Base(filepath.FromSlash(p.SubPath)))
+		}
+		res := &CopyResult{}
+		t, skipped, err := download(ctx, st, p, p.SubPath, version, dst, opts)
+		if err != nil {
+			return nil, err
+		}
+		res.add(t, skipped)
+		return res, nil
+	}
+
+	if opts.VersionID != "" {
+		return nil, fmt.Errorf("a version ID only applies to a remote source")
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access source: %w", err)
+	}
+	if info.IsDir() && !opts.Recursive {
+		return nil, fmt.Errorf("'%s' is a directory; copy it recursively", src)
+	}
+	p, st, err := c.resolveDestination(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return uploadDirectory(ctx, st, src, p, opts)
+	}
+	res := &CopyResult{}
+	t, skipped, err := upload(ctx, st, src, p, p.SubPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	res.add(t, skipped)
+	return res, nil
+}
+
+// upload copies a local file to the blob name in p's container, unless the
+// Overwrite policy skips it
+func upload(ctx context.Context, st Store, localPath string, p *azpath.BlobPath, name string, opts *CopyOptions) (Transfer, bool, error) {
+	t := Transfer{Source: localPath, Destination: p.BuildFull(name)}
+	putOpts := &store.PutOptions{LeaseID: opts.LeaseID}
+	if c := opts.Conditions; c != nil {
+		putOpts.IfMatch, putOpts.IfUnmodifiedSince = c.IfMatch, c.IfUnmodifiedSince
+		if c.NoClobber {
+			putOpts.IfNoneMatch = "*"
+		}
+	}
+	if opts.Overwrite != OverwriteAlways {
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return t, false, fmt.Errorf("failed to access local file: %w", err)
+		}
+		obj, err := st.Properties(ctx, p.Container, name, nil)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// Fail rather than overwrite a blob created in the meantime
+			putOpts.IfNoneMatch = "*"
+		case err != nil:
+			return t, false, fmt.Errorf("failed to get destination properties: %w", err)
+		case opts.Overwrite == OverwriteNever || !info.ModTime().After(obj.LastModified):
+			skip(t, opts)
+			return t, true, nil
+		default:
+			// Fail rather than overwrite a blob changed in the meantime
+			putOpts.IfMatch = obj.ETag
+		}
+	}
+
+	if opts.OnTransfer != nil {
+		opts.OnTransfer(t)
+	}
+	if opts.DryRun {
+		return t, false, nil
+	}
+
+	// The store computes the Content-MD5 from the bytes it uploads, so a
+	// file changed in the meantime cannot get a stale one
+	file, err := os.Open(localPath)
+	if err != nil {
+		return t, false, fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer file.Close()
+
+	counter := &countingReader{r: file}
+	err = st.Put(ctx, p.Container, name, counter, putOpts)
+	if opts.Overwrite == OverwriteNever && (errors.Is(err, ErrExists) || errors.Is(err, ErrConditionNotMet)) {
+		// Another writer created the blob since it was checked
+		skip(t, opts)
+		return t, true, nil
+	}
+	if err != nil {
+		return t, false, fmt.Errorf("upload failed for '%s': %w", t.Destination, err)
+	}
+	t.Size = counter.n
+	return t, false, nil
+}
+
+// skip reports a transfer the Overwrite policy skipped
+func skip(t Transfer, opts *CopyOptions) {
+	if opts.OnSkip != nil {
+		opts.OnSkip(t)
+	}
+}
+
+func uploadDirectory(ctx context.Context, st Store, localDir string, p *azpath.BlobPath, opts *CopyOptions) (*CopyResult, error) {
+	res := &CopyResult{}
+	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		if interrupted(opts.Interrupt) {
+			return ErrInterrupted
+		}
+		rel, err := filepath.Rel(localDir, path)
+		if err != nil {
+			return err
+		}
+		t, skipped, err := upload(ctx, st, path, p, joinBlobPath(p.SubPath, filepath.ToSlash(rel)), opts)
+		if err != nil {
+			return err
+		}
+		res.add(t, skipped)
+		return nil
+	})
+	if errors.Is(err, ErrInterrupted) {
+		return res, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("directory upload failed: %w", err)
+	}
+	return res, nil
+}
+
+// download copies blob name (at version, if set) to a local file, unless the
+// Overwrite policy skips it
+func download(ctx context.Context, st Store, p *azpath.BlobPath, name, version, localPath string, opts *CopyOptions) (Transfer, bool, error) {
+	t := Transfer{Source: p.BuildFullVersion(name, version), Destination: localPath}
+	if opts.Overwrite != OverwriteAlways {
+		if info, err := os.Stat(localPath); err == nil {
+			if opts.Overwrite == OverwriteNever {
+				skip(t, opts)
+				return t, true, nil
+			}
+			obj, err := st.Properties(ctx, p.Container, name, &store.GetOptions{VersionID: version})
+			if err != nil {
+				return t, false, fmt.Errorf("failed to get blob properties: %w", err)
+			}
+			if !obj.LastModified.After(info.ModTime()) {
+				skip(t, opts)
+				return t, true, nil
+			}
+		}
+	}
+
+	if opts.OnTransfer != nil {
+		opts.OnTransfer(t)
+	}
+	if opts.DryRun {
+		return t, false, nil
+	}
+
+	reader, obj, err := st.Get(ctx, p.Container, name, &store.GetOptions{VersionID: version})
+	if err != nil {
+		return t, false, fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return t, false, fmt.Errorf("failed to create local directory: %w", err)
+	}
+	r, check := verifyDownload(reader, obj, t.Source, opts.CheckMD5, opts.OnChecksumMismatch)
+	t.Size, err = writeFileAtomic(localPath, r, check)
+	if err != nil {
+		return t, false, fmt.Errorf("download failed: %w", err)
+	}
+	return t, false, nil
+}
+
+// writeFileAtomic writes r to path through a temporary file that is renamed
+// into place once check accepted the data, so an interrupted or corrupt
+// download never leaves a partial file behind
+func writeFileAtomic(path string, r io.Reader, check func() error) (int64, error) {
+	var n int64
+	err := atomicfile.WriteFile(path, 0666, func(w io.Writer) error {
+		var err error
+		if n, err = io.Copy(w, r); err != nil {
+			return err
+		}
+		return check()
+	})
+	return n, err
+}
+
+// interrupted reports whether ch is closed; a nil ch never is
+func interrupted(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func downloadDirectory(ctx context.Context, st Store, p *azpath.BlobPath, localDir string, opts *CopyOptions) (*CopyResult, error) {
+	prefix := p.DirPrefix()
+	var names []string
+	err := st.List(ctx, p.Container, store.ListOptions{Prefix: prefix}, func(obj Object) error {
+		names = append(names, obj.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list error: %w", err)
+	}
+
+	res := &CopyResult{}
+	for _, name := range names {
+		if interrupted(opts.Interrupt) {
+			return res, ErrInterrupted
+		}
+		rel := strings.TrimPrefix(name, prefix)
+		if isDirMarker(rel) {
+			continue
+		}
+		localPath, err := localPathFor(localDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		t, skipped, err := download(ctx, st, p, name, "", localPath, opts)
+		if err != nil {
+			return nil, fmt.Errorf("directory download failed: %w", err)
+		}
+		res.add(t, skipped)
+	}
+	return res, nil
+}
+
+// localPathFor returns the local path of the blob at rel, relative to a
+// downloaded prefix. Blob names come from the server, so a name that would
+// land outside localDir, like "../x" or "/etc/x", is rejected.
+func localPathFor(localDir, rel string) (string, error) {
+	local := filepath.FromSlash(rel)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("blob name '%s' would be written outside '%s'", rel, localDir)
+	}
+	return filepath.Join(localDir, local), nil
+}
+
+// isDirMarker reports whether a blob name is a directory marker, an empty
+// blob ending in "/" that some tools create for folders
+func isDirMarker(name string) bool {
+	return strings.HasSuffix(name, "/")
+}
+
+func joinBlobPath(dir, rel string) string {
+	if dir == "" {
+		return rel
+	}
+	return strings.TrimSuffix(dir, "/") + "/" + rel
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
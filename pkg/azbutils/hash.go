@@ -0,0 +1,196 @@
+package azbutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// HashAlgorithm names a checksum computed by Hash
+type HashAlgorithm string
+
+const (
+	HashMD5    HashAlgorithm = "md5"
+	HashSHA256 HashAlgorithm = "sha256"
+	// HashCRC64 is the CRC-64 variant of Azure Storage (polynomial
+	// 0x9A6C9329AC4BC9B5), as sent in x-ms-content-crc64
+	HashCRC64 HashAlgorithm = "crc64"
+)
+
+// SHA256MetadataKey is the metadata entry a backfill stores the SHA-256 in
+const SHA256MetadataKey = "sha256"
+
+var crc64Table = crc64.MakeTable(0x9A6C9329AC4BC9B5)
+
+// ParseHashAlgorithm parses "md5", "sha256" or "crc64"
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	switch a := HashAlgorithm(s); a {
+	case HashMD5, HashSHA256, HashCRC64:
+		return a, nil
+	}
+	return "", fmt.Errorf("invalid hash algorithm '%s' (want md5, sha256 or crc64)", s)
+}
+
+func (a HashAlgorithm) new() hash.Hash {
+	switch a {
+	case HashSHA256:
+		return sha256.New()
+	case HashCRC64:
+		return crc64.New(crc64Table)
+	default:
+		return md5.New()
+	}
+}
+
+// HashOptions configures Hash
+type HashOptions struct {
+	// Algorithm defaults to HashMD5
+	Algorithm HashAlgorithm
+	// Recursive hashes every blob under a prefix
+	Recursive bool
+	// Backfill stores the MD5 as the Content-MD5 of blobs that have none,
+	// and with HashSHA256 the SHA-256 in the metadata entry
+	// SHA256MetadataKey. A blob that changed while it was hashed is not
+	// updated; Hash fails instead.
+	Backfill bool
+	// LeaseID is required to backfill leased blobs
+	LeaseID string
+	// OnHash, if set, is called for each blob once it is hashed
+	OnHash func(BlobHash)
+}
+
+// BlobHash is the checksum of one blob
+type BlobHash struct {
+	// Path is the full remote path of the blob
+	Path string
+	// Name is the blob name relative to the hashed prefix; for a single
+	// blob, its base name
+	Name string
+	Sum  []byte
+	// Backfilled is set when Backfill updated the blob
+	Backfilled bool
+}
+
+// HashResult lists the hashed blobs in name order
+type HashResult struct {
+	Hashes     []BlobHash
+	Backfilled int
+}
+
+// Hash streams the blob at path, or every blob under it, through a checksum.
+// opts may be nil.
+func (c *Client) Hash(ctx context.Context, path string, opts *HashOptions) (*HashResult, error) {
+	if opts == nil {
+		opts = &HashOptions{}
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = HashMD5
+	}
+	if _, err := ParseHashAlgorithm(string(opts.Algorithm)); err != nil {
+		return nil, err
+	}
+	p, st, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &HashResult{}
+	if !opts.Recursive {
+		h, err := hashBlob(ctx, st, p, p.SubPath, baseName(p.SubPath), opts)
+		if err != nil {
+			return nil, err
+		}
+		res.add(h, opts)
+		return res, nil
+	}
+
+	prefix := p.DirPrefix()
+	var names []string
+	err = st.List(ctx, p.Container, store.ListOptions{Prefix: prefix}, func(obj Object) error {
+		names = append(names, obj.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list error: %w", err)
+	}
+	for _, name := range names {
+		h, err := hashBlob(ctx, st, p, name, strings.TrimPrefix(name, prefix), opts)
+		if err != nil {
+			return nil, err
+		}
+		res.add(h, opts)
+	}
+	return res, nil
+}
+
+func (r *HashResult) add(h BlobHash, opts *HashOptions) {
+	r.Hashes = append(r.Hashes, h)
+	if h.Backfilled {
+		r.Backfilled++
+	}
+	if opts.OnHash != nil {
+		opts.OnHash(h)
+	}
+}
+
+// hashBlob downloads blob name through the checksum and, with Backfill, the
+// MD5 it needs
+func hashBlob(ctx context.Context, st Store, p *azpath.BlobPath, name, rel string, opts *HashOptions) (BlobHash, error) {
+	h := BlobHash{Path: p.BuildFull(name), Name: rel}
+	reader, obj, err := st.Get(ctx, p.Container, name, &store.GetOptions{})
+	if err != nil {
+		return h, fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer reader.Close()
+
+	sum := opts.Algorithm.new()
+	md5Sum, w := sum, io.Writer(sum)
+	if opts.Backfill && opts.Algorithm != HashMD5 {
+		md5Sum = md5.New()
+		w = io.MultiWriter(sum, md5Sum)
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		return h, fmt.Errorf("failed to hash blob '%s': %w", h.Path, err)
+	}
+	h.Sum = sum.Sum(nil)
+	if !opts.Backfill {
+		return h, nil
+	}
+
+	// The update is pinned to the ETag of the hashed data
+	update := store.UpdateOptions{IfMatch: obj.ETag, LeaseID: opts.LeaseID}
+	actual := md5Sum.Sum(nil)
+	switch {
+	case len(obj.ContentMD5) == 0:
+		update.ContentMD5 = actual
+	case !bytes.Equal(obj.ContentMD5, actual):
+		return h, &ChecksumError{Path: h.Path, Expected: obj.ContentMD5, Actual: actual}
+	}
+	if opts.Algorithm == HashSHA256 {
+		update.Metadata = map[string]string{SHA256MetadataKey: hex.EncodeToString(h.Sum)}
+	}
+	if update.ContentMD5 == nil && update.Metadata == nil {
+		return h, nil
+	}
+	if err := st.Update(ctx, p.Container, name, update); err != nil {
+		return h, fmt.Errorf("failed to backfill '%s': %w", h.Path, err)
+	}
+	h.Backfilled = true
+	return h, nil
+}
+
+// baseName returns the last element of a blob name
+func baseName(name string) string {
+	return path.Base(strings.TrimSuffix(name, "/"))
+}
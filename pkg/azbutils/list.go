@@ -0,0 +1,87 @@
+package azbutils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/orionnectar/go-azbutils/internal/azpath"
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// ListOptions configures List
+type ListOptions struct {
+	// Recursive lists every blob under the path instead of one level
+	Recursive bool
+	// Versions includes every version of each blob
+	Versions bool
+	// Deleted includes soft-deleted blobs
+	Deleted bool
+}
+
+// Entry is a listed blob or virtual directory
+type Entry struct {
+	Object
+	// URL is the full path of the entry in the form the path was given
+	// (az:// or https://), pinned to its version when versions are listed
+	URL string
+}
+
+// ListResult holds the entries under a path
+type ListResult struct {
+	Account   string
+	Container string
+	Prefix    string
+	// Entries lists virtual directories first, then blobs
+	Entries []Entry
+}
+
+// List lists the blobs under path. opts may be nil. It holds every entry in
+// memory; use ListFunc for large containers.
+func (c *Client) List(ctx context.Context, path string, opts *ListOptions) (*ListResult, error) {
+	p, st, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	res := &ListResult{Account: p.Account, Container: p.Container, Prefix: p.SubPath}
+	err = list(ctx, st, p, opts, func(entry Entry) error {
+		res.Entries = append(res.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListFunc calls fn for each blob or virtual directory under path as the
+// listing pages arrive, virtual directories first. An error returned by fn
+// stops the listing and is returned. opts may be nil.
+func (c *Client) ListFunc(ctx context.Context, path string, opts *ListOptions, fn func(Entry) error) error {
+	p, st, err := c.resolve(path)
+	if err != nil {
+		return err
+	}
+	return list(ctx, st, p, opts, fn)
+}
+
+func list(ctx context.Context, st Store, p *azpath.BlobPath, opts *ListOptions, fn func(Entry) error) error {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	listOpts := store.ListOptions{Prefix: p.SubPath, Versions: opts.Versions, Deleted: opts.Deleted}
+	if !opts.Recursive {
+		listOpts.Delimiter = "/"
+	}
+
+	err := st.List(ctx, p.Container, listOpts, func(obj Object) error {
+		entry := Entry{Object: obj, URL: p.BuildFull(obj.Name)}
+		if opts.Versions {
+			entry.URL = p.BuildFullVersion(obj.Name, obj.VersionID)
+		}
+		return fn(entry)
+	})
+	if err != nil {
+		return fmt.Errorf("list error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package azbutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// SyncOptions configures Sync
+type SyncOptions struct {
+	// Delete removes destination files that do not exist in the source
+	Delete bool
+	// DryRun reports the changes without performing them
+	DryRun bool
+	// LeaseID is required to overwrite leased destination blobs
+	LeaseID string
+	// OnTransfer, if set, is called before each file is copied
+	OnTransfer func(Transfer)
+	// OnDelete, if set, is called before each destination file is deleted
+	OnDelete func(path string)
+	// CheckMD5 and OnChecksumMismatch check downloads as in CopyOptions
+	CheckMD5           ChecksumMode
+	OnChecksumMismatch func(*ChecksumError)
+	// Interrupt, when closed, stops the sync once the transfer in flight
+	// completed; Sync then returns the changes made so far along with
+	// ErrInterrupted
+	Interrupt <-chan struct{}
+}
+
+// SyncResult lists the changes made by Sync
+type SyncResult struct {
+	Copied  []Transfer
+	Deleted []string
+	// Skipped counts files that were already up to date
+	Skipped int
+	Bytes   int64
+}
+
+// syncFile is a file on either side of a sync, keyed by its relative path
+type syncFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// Sync makes the destination directory or prefix match the source, one way.
+// A file is copied when it is missing from the destination, differs in size
+// or is newer in the source. opts may be nil.
+func (c *Client) Sync(ctx context.Context, src, dst string, opts *SyncOptions) (*SyncResult, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	copyOpts := &CopyOptions{
+		DryRun:             opts.DryRun,
+		LeaseID:            opts.LeaseID,
+		OnTransfer:         opts.OnTransfer,
+		CheckMD5:           opts.CheckMD5,
+		OnChecksumMismatch: opts.OnChecksumMismatch,
+		Interrupt:          opts.Interrupt,
+	}
+
+	switch {
+	case IsRemote(src) && IsRemote(dst):
+		return nil, fmt.Errorf("syncing between two remote paths is not supported")
+	case IsRemote(src):
+		p, st, err := c.resolve(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source path: %w", err)
+		}
+		prefix := p.DirPrefix()
+		remote, err := remoteFiles(ctx, st, p.Container, prefix, false)
+		if err != nil {
+			return nil, err
+		}
+		local, err := localFiles(dst)
+		if err != nil {
+			return nil, err
+		}
+
+		res := &SyncResult{}
+		for _, rel := range changed(remote, local, res) {
+			if interrupted(opts.Interrupt) {
+				return res, ErrInterrupted
+			}
+			localPath, err := localPathFor(dst, rel)
+			if err != nil {
+				return nil, err
+			}
+			t, _, err := download(ctx, st, p, prefix+rel, "", localPath, copyOpts)
+			if err != nil {
+				return nil, err
+			}
+			res.add(t)
+		}
+		if opts.Delete {
+			for _, rel := range extra(remote, local) {
+				if interrupted(opts.Interrupt) {
+					return res, ErrInterrupted
+				}
+				path := filepath.Join(dst, filepath.FromSlash(rel))
+				if err := res.remove(path, opts, func() error { return os.Remove(path) }); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return res, nil
+	default:
+		info, err := os.Stat(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access source: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("sync source '%s' must be a directory", src)
+		}
+		p, st, err := c.resolveDestination(dst)
+		if err != nil {
+			return nil, err
+		}
+		prefix := p.DirPrefix()
+		local, err := localFiles(src)
+		if err != nil {
+			return nil, err
+		}
+		remote, err := remoteFiles(ctx, st, p.Container, prefix, true)
+		if err != nil {
+			return nil, err
+		}
+
+		res := &SyncResult{}
+		for _, rel := range changed(local, remote, res) {
+			if interrupted(opts.Interrupt) {
+				return res, ErrInterrupted
+			}
+			t, _, err := upload(ctx, st, filepath.Join(src, filepath.FromSlash(rel)), p, prefix+rel, copyOpts)
+			if err != nil {
+				return nil, err
+			}
+			res.add(t)
+		}
+		if opts.Delete {
+			for _, rel := range extra(local, remote) {
+				if interrupted(opts.Interrupt) {
+					return res, ErrInterrupted
+				}
+				name := prefix + rel
+				err := res.remove(p.BuildFull(name), opts, func() error {
+					return st.Delete(ctx, p.Container, name, &store.DeleteOptions{LeaseID: opts.LeaseID})
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		return res, nil
+	}
+}
+
+func (r *SyncResult) add(t Transfer) {
+	r.Copied = append(r.Copied, t)
+	r.Bytes += t.Size
+}
+
+func (r *SyncResult) remove(path string, opts *SyncOptions, del func() error) error {
+	if opts.OnDelete != nil {
+		opts.OnDelete(path)
+	}
+	if !opts.DryRun {
+		if err := del(); err != nil {
+			return fmt.Errorf("failed to delete '%s': %w", path, err)
+		}
+	}
+	r.Deleted = append(r.Deleted, path)
+	return nil
+}
+
+// changed returns the sorted source paths missing from or out of date in dst,
+// counting the others as skipped
+func changed(src, dst map[string]syncFile, res *SyncResult) []string {
+	var paths []string
+	for rel, s := range src {
+		d, ok := dst[rel]
+		if ok && d.size == s.size && !s.modTime.After(d.modTime) {
+			res.Skipped++
+			continue
+		}
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// extra returns the sorted destination paths that are not in src
+func extra(src, dst map[string]syncFile) []string {
+	var paths []string
+	for rel := range dst {
+		if _, ok := src[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func localFiles(dir string) (map[string]syncFile, error) {
+	files := make(map[string]syncFile)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && path == dir {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = syncFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// remoteFiles lists the blobs under prefix, keyed by their relative names
+func remoteFiles(ctx context.Context, st Store, container, prefix string, missingOK bool) (map[string]syncFile, error) {
+	files := make(map[string]syncFile)
+	err := walkRemote(ctx, st, container, prefix, missingOK, func(rel string, obj Object) {
+		files[rel] = syncFile{size: obj.Size, modTime: obj.LastModified}
+	})
+	return files, err
+}
+
+// walkRemote calls fn with the relative name of each blob under prefix,
+// skipping directory markers. With missingOK, a missing container lists as
+// empty, as the destination of an upload may not exist yet; a missing source
+// must fail rather than look empty, or Delete would remove every local file.
+func walkRemote(ctx context.Context, st Store, container, prefix string, missingOK bool, fn func(rel string, obj Object)) error {
+	err := st.List(ctx, container, store.ListOptions{Prefix: prefix}, func(obj Object) error {
+		if rel := strings.TrimPrefix(obj.Name, prefix); rel != "" && !isDirMarker(rel) {
+			fn(rel, obj)
+		}
+		return nil
+	})
+	if err != nil && !(missingOK && errors.Is(err, ErrNotFound)) {
+		return fmt.Errorf("list error: %w", err)
+	}
+	return nil
+}
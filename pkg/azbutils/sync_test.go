@@ -0,0 +1,87 @@
+package azbutils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncMissingSourceKeepsLocalFiles(t *testing.T) {
+	c := newTestClient(t, NewMemoryStore())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"precious.txt": "keep"})
+
+	_, err := c.Sync(context.Background(), "az://acct//typo", dir, &SyncOptions{Delete: true})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "precious.txt")); err != nil {
+		t.Errorf("local file was touched: %v", err)
+	}
+}
+
+func TestSyncUploadToMissingContainer(t *testing.T) {
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "alpha"})
+
+	res, err := c.Sync(context.Background(), dir, "az://acct//new/site", &SyncOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(res.Copied) != 1 || len(res.Deleted) != 0 {
+		t.Errorf("result = %+v, want one upload", res)
+	}
+}
+
+func TestSyncRejectsEscapingNames(t *testing.T) {
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "d/../../escape.txt", "out")
+	putBlob(t, st, "data", "d/sub/", "")
+
+	out := t.TempDir()
+	dst := filepath.Join(out, "a", "b")
+	if _, err := c.Sync(context.Background(), "az://acct//data/d", dst, nil); err == nil {
+		t.Error("sync of an escaping blob name succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(out, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("escape.txt was written outside the destination")
+	}
+}
+
+func TestVerifyDirectory(t *testing.T) {
+	st := NewMemoryStore()
+	c := newTestClient(t, st)
+	putBlob(t, st, "data", "d/same.txt", "same")
+	putBlob(t, st, "data", "d/diff.txt", "blob")
+	putBlob(t, st, "data", "d/remote.txt", "r")
+	putBlob(t, st, "data", "d/sub/", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"same.txt": "same", "diff.txt": "file", "local.txt": "l"})
+
+	res, err := c.Verify(context.Background(), dir, "az://acct//data/d", nil)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	want := map[string]VerifyStatus{
+		"diff.txt":   VerifyMismatch,
+		"local.txt":  VerifyMissingRemote,
+		"remote.txt": VerifyMissingLocal,
+		"same.txt":   VerifyMatch,
+	}
+	if len(res.Entries) != len(want) || res.Differences != 3 {
+		t.Fatalf("result = %+v, want %v", res, want)
+	}
+	for _, e := range res.Entries {
+		if e.Status != want[e.Path] {
+			t.Errorf("%s: %s, want %s", e.Path, e.Status, want[e.Path])
+		}
+		if e.Downloaded {
+			t.Errorf("%s was downloaded although its stored MD5 was listed", e.Path)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package azbutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/orionnectar/go-azbutils/internal/store"
+)
+
+// VerifyStatus is the outcome of comparing one file with its blob
+type VerifyStatus int
+
+const (
+	VerifyMatch VerifyStatus = iota
+	VerifyMismatch
+	// VerifyMissingRemote is a local file without a blob
+	VerifyMissingRemote
+	// VerifyMissingLocal is a blob without a local file
+	VerifyMissingLocal
+)
+
+func (s VerifyStatus) String() string {
+	switch s {
+	case VerifyMatch:
+		return "OK"
+	case VerifyMismatch:
+		return "MISMATCH"
+	case VerifyMissingRemote:
+		return "MISSING-REMOTE"
+	default:
+		return "MISSING-LOCAL"
+	}
+}
+
+// VerifyEntry is one compared file, by its path relative to the compared
+// directory and prefix
+type VerifyEntry struct {
+	Path   string
+	Status VerifyStatus
+	// Downloaded is set when the blob's contents were downloaded to hash
+	// them, because it had no stored MD5 or VerifyOptions.Download is set
+	Downloaded bool
+}
+
+// VerifyOptions configures Verify
+type VerifyOptions struct {
+	// Download hashes the contents of every blob of the local file's size,
+	// rather than trusting stored MD5s
+	Download bool
+	// OnEntry, if set, is called for each file once it is compared
+	OnEntry func(VerifyEntry)
+}
+
+// VerifyResult lists the compared files in path order
+type VerifyResult struct {
+	Entries []VerifyEntry
+	// Differences counts the entries that are not VerifyMatch
+	Differences int
+}
+
+// Verify compares a local file or directory with a blob or prefix by MD5.
+// Files of different sizes are not hashed; blobs without a stored MD5 are
+// downloaded and hashed. opts may be nil.
+func (c *Client) Verify(ctx context.Context, local, remote string, opts *VerifyOptions) (*VerifyResult, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+	if IsRemote(local) || !IsRemote(remote) {
+		return nil, fmt.Errorf("verify compares a local path with a remote path, in that order")
+	}
+	p, st, err := c.resolve(remote)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+	info, err := os.Stat(local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access local path: %w", err)
+	}
+
+	// A single file is keyed by its blob name, a directory by relative paths
+	var prefix string
+	var locals map[string]syncFile
+	remotes := make(map[string]Object)
+	localPath := func(string) string { return local }
+	if info.IsDir() {
+		prefix = p.DirPrefix()
+		if locals, err = localFiles(local); err != nil {
+			return nil, err
+		}
+		err = walkRemote(ctx, st, p.Container, prefix, true, func(rel string, obj Object) {
+			remotes[rel] = obj
+		})
+		if err != nil {
+			return nil, err
+		}
+		localPath = func(rel string) string { return filepath.Join(local, filepath.FromSlash(rel)) }
+	} else {
+		locals = map[string]syncFile{p.SubPath: {size: info.Size()}}
+		obj, err := st.Properties(ctx, p.Container, p.SubPath, nil)
+		switch {
+		case err == nil:
+			remotes[p.SubPath] = *obj
+		case !errors.Is(err, ErrNotFound):
+			return nil, fmt.Errorf("failed to get blob properties: %w", err)
+		}
+	}
+
+	paths := make([]string, 0, len(locals))
+	for rel := range locals {
+		paths = append(paths, rel)
+	}
+	for rel := range remotes {
+		if _, ok := locals[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	res := &VerifyResult{}
+	for _, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		l, inLocal := locals[rel]
+		r, inRemote := remotes[rel]
+		e := VerifyEntry{Path: rel}
+		switch {
+		case !inRemote:
+			e.Status = VerifyMissingRemote
+		case !inLocal:
+			e.Status = VerifyMissingLocal
+		case l.size != r.Size:
+			e.Status = VerifyMismatch
+		default:
+			localSum, err := fileMD5(localPath(rel))
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash local file: %w", err)
+			}
+			remoteSum := r.ContentMD5
+			if len(remoteSum) == 0 || opts.Download {
+				e.Downloaded = true
+				if remoteSum, err = blobMD5(ctx, st, p.Container, prefix+rel); err != nil {
+					return nil, err
+				}
+			}
+			if !bytes.Equal(localSum, remoteSum) {
+				e.Status = VerifyMismatch
+			}
+		}
+		if e.Status != VerifyMatch {
+			res.Differences++
+		}
+		res.Entries = append(res.Entries, e)
+		if opts.OnEntry != nil {
+			opts.OnEntry(e)
+		}
+	}
+	return res, nil
+}
+
+// blobMD5 downloads a blob and returns the MD5 of its contents
+func blobMD5(ctx context.Context, st Store, container, name string) ([]byte, error) {
+	reader, _, err := st.Get(ctx, container, name, &store.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer reader.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, reader); err != nil {
+		return nil, fmt.Errorf("failed to hash blob: %w", err)
+	}
+	return h.Sum(nil), nil
+}